@@ -1,15 +1,16 @@
 package cmd
 
 import (
-	"encoding/json"
 	"fmt"
 	"strings"
 
-	"sc/internal/ipc"
+	"sc/internal/config"
 
 	"github.com/spf13/cobra"
 )
 
+var addGroup string
+
 var addCmd = &cobra.Command{
 	Use:   "add <domain...>",
 	Short: "Add domains to the block list",
@@ -18,31 +19,31 @@ var addCmd = &cobra.Command{
 }
 
 func init() {
+	addCmd.Flags().StringVar(&addGroup, "group", "", "also add the domains to this group")
 	rootCmd.AddCommand(addCmd)
 }
 
 func runAdd(cmd *cobra.Command, args []string) error {
-	client := newClient()
-	resp, err := client.Send(ipc.Request{
-		Command: ipc.CmdAdd,
-		Args:    map[string]string{"domains": strings.Join(args, ",")},
-	})
+	ctx, cancel := requestContext(cmd)
+	defer cancel()
+	data, err := newClient().Add(ctx, args, addGroup)
 	if err != nil {
 		return err
 	}
-	if !resp.OK {
-		return fmt.Errorf("daemon: %s", resp.Error)
-	}
 
-	raw, _ := json.Marshal(resp.Data)
-	var data ipc.MutateData
-	json.Unmarshal(raw, &data)
+	if ok, err := printStructured(data); ok {
+		return err
+	}
 
 	if len(data.Added) == 0 {
 		fmt.Println("All domains already in block list")
 	} else {
 		for _, d := range data.Added {
-			fmt.Printf("Added %s\n", d)
+			if addGroup != "" {
+				fmt.Printf("Added %s to %s%s\n", d, config.GroupPrefix, strings.TrimPrefix(addGroup, config.GroupPrefix))
+			} else {
+				fmt.Printf("Added %s\n", d)
+			}
 		}
 	}
 	return nil
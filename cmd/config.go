@@ -1,9 +1,13 @@
 package cmd
 
 import (
+	"bufio"
+	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"strings"
 
 	"sc/internal/config"
 
@@ -26,23 +30,30 @@ var configPathCmd = &cobra.Command{
 
 var configEditCmd = &cobra.Command{
 	Use:   "edit",
-	Short: "Open config in $EDITOR",
-	RunE: func(cmd *cobra.Command, args []string) error {
-		editor := os.Getenv("EDITOR")
-		if editor == "" {
-			editor = "vim"
-		}
-		c := exec.Command(editor, config.ConfigPath())
-		c.Stdin = os.Stdin
-		c.Stdout = os.Stdout
-		c.Stderr = os.Stderr
-		return c.Run()
-	},
+	Short: "Edit the config in $EDITOR and apply it through the daemon",
+	Long:  "Open a copy of the config in $EDITOR. When the editor exits the copy is validated, the changes are shown, and on confirmation the daemon applies them and writes the config file. Changes that loosen blocking are refused during a focus session, and for domains that are cooling down or out of budget.",
+	RunE:  runConfigEdit,
+}
+
+var configReloadCmd = &cobra.Command{
+	Use:   "reload",
+	Short: "Make the daemon re-read its config file",
+	RunE:  runConfigReload,
+}
+
+var configCheckCmd = &cobra.Command{
+	Use:          "check [file]",
+	Short:        "Validate a config file (default: the active one)",
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE:         runConfigCheck,
 }
 
 func init() {
 	configCmd.AddCommand(configPathCmd)
 	configCmd.AddCommand(configEditCmd)
+	configCmd.AddCommand(configReloadCmd)
+	configCmd.AddCommand(configCheckCmd)
 	rootCmd.AddCommand(configCmd)
 }
 
@@ -61,3 +72,165 @@ func runConfigShow(cmd *cobra.Command, args []string) error {
 	fmt.Print(string(data))
 	return nil
 }
+
+func runConfigReload(cmd *cobra.Command, args []string) error {
+	ctx, cancel := requestContext(cmd)
+	defer cancel()
+	data, err := newClient().Reload(ctx)
+	if err != nil {
+		return err
+	}
+
+	if ok, err := printStructured(data); ok {
+		return err
+	}
+
+	if len(data.Changes) == 0 {
+		fmt.Println("Config reloaded, no changes.")
+		return nil
+	}
+	fmt.Println("Config reloaded:")
+	for _, c := range data.Changes {
+		fmt.Printf("  %s\n", c)
+	}
+	return nil
+}
+
+func runConfigCheck(cmd *cobra.Command, args []string) error {
+	path := config.ConfigPath()
+	if len(args) == 1 {
+		path = args[0]
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	result := struct {
+		File     string              `json:"file"`
+		Valid    bool                `json:"valid"`
+		Problems []config.FieldError `json:"problems"`
+	}{File: path, Valid: true, Problems: []config.FieldError{}}
+
+	_, err = config.Parse(data)
+	var verr *config.ValidationError
+	if err != nil && !errors.As(err, &verr) {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if verr != nil {
+		result.Valid = false
+		result.Problems = verr.Errors
+	}
+
+	if ok, perr := printStructured(result); ok {
+		if perr == nil && verr != nil {
+			perr = fmt.Errorf("%s: %d problem(s) found", path, len(verr.Errors))
+		}
+		return perr
+	}
+
+	if verr != nil {
+		for _, fe := range verr.Errors {
+			fmt.Printf("%s: %s\n", path, fe)
+		}
+		return fmt.Errorf("%s: %d problem(s) found", path, len(verr.Errors))
+	}
+
+	fmt.Printf("%s: OK\n", path)
+	return nil
+}
+
+func runConfigEdit(cmd *cobra.Command, args []string) error {
+	orig, err := os.ReadFile(config.ConfigPath())
+	if err != nil {
+		return err
+	}
+	current, err := config.Parse(orig)
+	if err != nil {
+		current = config.Default()
+	}
+
+	tmp, err := os.CreateTemp("", "sc-config-*.yaml")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	_, err = tmp.Write(orig)
+	tmp.Close()
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	ask := func(prompt string) bool {
+		fmt.Printf("%s [y/N] ", prompt)
+		answer, _ := reader.ReadString('\n')
+		answer = strings.TrimSpace(strings.ToLower(answer))
+		return answer == "y" || answer == "yes"
+	}
+
+	var edited []byte
+	var next *config.Config
+	for {
+		if err := runEditor(tmpPath); err != nil {
+			os.Remove(tmpPath)
+			return err
+		}
+		if edited, err = os.ReadFile(tmpPath); err != nil {
+			os.Remove(tmpPath)
+			return err
+		}
+		if bytes.Equal(edited, orig) {
+			os.Remove(tmpPath)
+			fmt.Println("No changes.")
+			return nil
+		}
+		if next, err = config.Parse(edited); err == nil {
+			break
+		}
+		fmt.Println(err)
+		if !ask("Edit again?") {
+			fmt.Printf("Config not changed. Your edits are in %s\n", tmpPath)
+			return nil
+		}
+	}
+
+	changes := config.Diff(current, next)
+	if len(changes) == 0 {
+		fmt.Println("No setting changes (comments or formatting only).")
+	} else {
+		fmt.Println("Changes:")
+		for _, c := range changes {
+			fmt.Printf("  %s\n", c)
+		}
+	}
+	if !ask("Apply?") {
+		fmt.Printf("Config not changed. Your edits are in %s\n", tmpPath)
+		return nil
+	}
+
+	ctx, cancel := requestContext(cmd)
+	defer cancel()
+	if _, err := newClient().ApplyConfig(ctx, edited); err != nil {
+		fmt.Printf("Config not changed. Your edits are in %s\n", tmpPath)
+		return err
+	}
+
+	os.Remove(tmpPath)
+	fmt.Println("Config applied.")
+	return nil
+}
+
+func runEditor(path string) error {
+	editor := os.Getenv("EDITOR")
+	if editor == "" {
+		editor = "vim"
+	}
+	c := exec.Command(editor, path)
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	return c.Run()
+}
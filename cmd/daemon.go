@@ -36,12 +36,23 @@ func runDaemon(cmd *cobra.Command, args []string) error {
 	d := daemon.New(cfg, cfgPath, logger)
 
 	sockPath := config.SocketPath()
-	srv := daemon.NewServer(d, sockPath, logger)
+	srv := daemon.NewServer(d, sockPath, version, logger)
 	if err := srv.Start(); err != nil {
 		return err
 	}
 	defer srv.Stop()
 
+	if api := cfg.Settings.HTTPAPI; api.Listen != "" {
+		hs, err := daemon.NewHTTPServer(srv, api, logger)
+		if err != nil {
+			return err
+		}
+		if err := hs.Start(); err != nil {
+			return err
+		}
+		defer hs.Stop()
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -54,5 +65,15 @@ func runDaemon(cmd *cobra.Command, args []string) error {
 		cancel()
 	}()
 
+	hupCh := make(chan os.Signal, 1)
+	signal.Notify(hupCh, syscall.SIGHUP)
+
+	go func() {
+		for range hupCh {
+			logger.Info().Msg("received SIGHUP, reloading config")
+			d.Reload("sighup", nil, nil)
+		}
+	}()
+
 	return d.Run(ctx)
 }
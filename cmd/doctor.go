@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+
+	"sc/internal/blocker"
+	"sc/internal/config"
+
+	"github.com/spf13/cobra"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:          "doctor",
+	Short:        "Check the daemon and whether blocks can be bypassed on this machine",
+	SilenceUsage: true,
+	RunE:         runDoctor,
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+}
+
+// doctorCheck is one line of the doctor report.
+type doctorCheck struct {
+	Status  string   `json:"status"`
+	Message string   `json:"message"`
+	Details []string `json:"details,omitempty"`
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	var checks []doctorCheck
+	problems := 0
+	report := func(ok bool, format string, a ...interface{}) *doctorCheck {
+		status := "ok"
+		if !ok {
+			status = "fail"
+			problems++
+		}
+		checks = append(checks, doctorCheck{Status: status, Message: fmt.Sprintf(format, a...)})
+		return &checks[len(checks)-1]
+	}
+	note := func(format string, a ...interface{}) {
+		checks = append(checks, doctorCheck{Status: "warn", Message: fmt.Sprintf(format, a...)})
+	}
+
+	cfg, err := config.Read(config.ConfigPath())
+	c := report(err == nil, "config %s loads", config.ConfigPath())
+	if err != nil {
+		c.Details = append(c.Details, err.Error())
+		cfg = config.Default()
+	}
+
+	ctx, cancel := requestContext(cmd)
+	defer cancel()
+	hello, err := newClient().Hello(ctx)
+	c = report(err == nil, "daemon is reachable at %s", config.SocketPath())
+	if err != nil {
+		c.Details = append(c.Details, err.Error())
+	} else {
+		c.Details = append(c.Details, fmt.Sprintf("daemon version %s, protocol %d", hello.DaemonVersion, hello.ProtocolVersion))
+	}
+	c.Details = append(c.Details, fmt.Sprintf("backends: %v", cfg.Settings.Backends))
+
+	// DNS over HTTPS
+	report(cfg.Settings.BlockDoH, "DoH protection enabled (settings.block_doh)")
+
+	resolved := resolveAll(blocker.DoHEndpoints)
+	var reachable []string
+	for _, host := range blocker.DoHEndpoints {
+		if resolved[host] {
+			reachable = append(reachable, host)
+		}
+	}
+	c = report(len(reachable) == 0, "%d of %d known DoH endpoints resolve", len(reachable), len(blocker.DoHEndpoints))
+	c.Details = append(c.Details, reachable...)
+
+	canaryErr := checkNXDomain(blocker.FirefoxCanary)
+	c = report(canaryErr == nil, "Firefox canary %s returns NXDOMAIN", blocker.FirefoxCanary)
+	if canaryErr != nil {
+		c.Details = append(c.Details, canaryErr.Error(),
+			"Firefox will enable DNS over HTTPS by default and skip sc's blocks.")
+	}
+
+	if !cfg.Settings.UsesBackend(config.BackendDNS) {
+		note("only the dns backend answers the Firefox canary with NXDOMAIN; other backends cannot turn Firefox's default DoH off")
+	}
+	if !cfg.Settings.UsesBackend(config.BackendNFTables) {
+		note("DoH servers contacted by IP address (e.g. 1.1.1.1) are not blocked without the nftables backend")
+	}
+
+	bypass := len(reachable) > 0 || canaryErr != nil
+	printed, err := printStructured(struct {
+		Checks    []doctorCheck `json:"checks"`
+		DoHBypass bool          `json:"doh_bypass"`
+		Problems  int           `json:"problems"`
+	}{checks, bypass, problems})
+	if err != nil {
+		return err
+	}
+
+	if !printed {
+		for _, c := range checks {
+			label := map[string]string{"ok": "ok  ", "fail": "FAIL", "warn": "warn"}[c.Status]
+			fmt.Printf("[%s] %s\n", label, c.Message)
+			for _, d := range c.Details {
+				fmt.Printf("       %s\n", d)
+			}
+		}
+
+		fmt.Println()
+		if bypass {
+			fmt.Println("DoH bypass is possible on this machine.")
+		} else {
+			fmt.Println("No DoH bypass detected.")
+		}
+	}
+	if problems > 0 {
+		return fmt.Errorf("doctor found %d problem(s)", problems)
+	}
+	return nil
+}
+
+// resolveAll looks hosts up through the system resolver and reports which
+// of them resolve to a usable address (not 0.0.0.0, ::, or loopback).
+func resolveAll(hosts []string) map[string]bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	result := make(map[string]bool, len(hosts))
+	for _, host := range hosts {
+		wg.Add(1)
+		go func(host string) {
+			defer wg.Done()
+			addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+			ok := false
+			if err == nil {
+				for _, a := range addrs {
+					if usable(a) {
+						ok = true
+						break
+					}
+				}
+			}
+			mu.Lock()
+			result[host] = ok
+			mu.Unlock()
+		}(host)
+	}
+	wg.Wait()
+	return result
+}
+
+// checkNXDomain returns nil if host fails to resolve with NXDOMAIN. Any
+// answer counts against it, including the 0.0.0.0 the hosts backend
+// writes, since Firefox only turns DoH off on NXDOMAIN.
+func checkNXDomain(host string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err == nil {
+		return fmt.Errorf("%s resolves to %v", host, addrs)
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return nil
+	}
+	return err
+}
+
+func usable(a netip.Addr) bool {
+	a = a.Unmap()
+	return !a.IsUnspecified() && !a.IsLoopback()
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var focusSkipConfirm bool
+
+var focusCmd = &cobra.Command{
+	Use:   "focus <duration>",
+	Short: "Reblock everything and lock sc until the session ends",
+	Long:  "Start a focus session: all domains are reblocked immediately and every unblock, remove or loosening request is refused until the session ends. Sessions survive daemon restarts and config edits, and can only be extended, never cancelled.",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runFocus,
+}
+
+func init() {
+	focusCmd.Flags().BoolVarP(&focusSkipConfirm, "yes", "y", false, "Skip confirmation prompt")
+	rootCmd.AddCommand(focusCmd)
+}
+
+func runFocus(cmd *cobra.Command, args []string) error {
+	dur, err := time.ParseDuration(args[0])
+	if err != nil || dur <= 0 {
+		return fmt.Errorf("invalid duration: %s", args[0])
+	}
+
+	if !focusSkipConfirm {
+		reader := bufio.NewReader(os.Stdin)
+		fmt.Fprintf(messages(), "Focus for %s? This cannot be cancelled. [y/N] ", dur)
+		answer, _ := reader.ReadString('\n')
+		answer = strings.TrimSpace(strings.ToLower(answer))
+		if answer != "y" && answer != "yes" {
+			fmt.Fprintln(messages(), "Cancelled.")
+			return nil
+		}
+	}
+
+	ctx, cancel := requestContext(cmd)
+	defer cancel()
+	data, err := newClient().Focus(ctx, dur)
+	if err != nil {
+		return err
+	}
+
+	if ok, err := printStructured(data); ok {
+		return err
+	}
+
+	for _, d := range data.Reblocked {
+		fmt.Printf("Reblocked %s\n", d)
+	}
+	until := data.Until
+	if t, err := time.Parse(time.RFC3339, data.Until); err == nil {
+		until = t.Format("Mon 15:04")
+	}
+	fmt.Printf("Focus session active until %s (%s).\n", until, data.Remaining)
+	return nil
+}
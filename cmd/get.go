@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"sc/internal/config"
+
+	"github.com/spf13/cobra"
+)
+
+var getCmd = &cobra.Command{
+	Use:       "get <key>",
+	Short:     "Print a setting from the running daemon",
+	Long:      "Print the value of a single config setting as the daemon currently uses it. Keys:\n\n  " + strings.Join(config.Keys(), "\n  "),
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: config.Keys(),
+	RunE:      runGet,
+}
+
+func init() {
+	rootCmd.AddCommand(getCmd)
+}
+
+func runGet(cmd *cobra.Command, args []string) error {
+	ctx, cancel := requestContext(cmd)
+	defer cancel()
+	data, err := newClient().Get(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	if ok, err := printStructured(data); ok {
+		return err
+	}
+
+	fmt.Println(data.Value)
+	return nil
+}
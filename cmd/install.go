@@ -3,44 +3,17 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"text/template"
 
 	"sc/internal/config"
+	"sc/internal/service"
 
 	"github.com/spf13/cobra"
 )
 
-const plistTemplate = `<?xml version="1.0" encoding="UTF-8"?>
-<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN"
-  "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
-<plist version="1.0">
-<dict>
-    <key>Label</key>
-    <string>com.sc.daemon</string>
-    <key>ProgramArguments</key>
-    <array>
-        <string>{{.BinaryPath}}</string>
-        <string>daemon</string>
-    </array>
-    <key>RunAtLoad</key>
-    <true/>
-    <key>KeepAlive</key>
-    <true/>
-    <key>StandardOutPath</key>
-    <string>{{.LogPath}}</string>
-    <key>StandardErrorPath</key>
-    <string>{{.LogPath}}</string>
-</dict>
-</plist>
-`
-
-const plistPath = "/Library/LaunchDaemons/com.sc.daemon.plist"
-
 var installCmd = &cobra.Command{
 	Use:   "install",
-	Short: "Install launchd daemon (requires sudo)",
+	Short: "Install the daemon as a launchd/systemd service (requires sudo)",
 	RunE:  runInstall,
 }
 
@@ -53,6 +26,11 @@ func runInstall(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("install requires root — run: sudo sc install")
 	}
 
+	mgr, err := service.New()
+	if err != nil {
+		return err
+	}
+
 	exe, err := os.Executable()
 	if err != nil {
 		return fmt.Errorf("cannot determine binary path: %w", err)
@@ -76,35 +54,19 @@ func runInstall(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	tmpl, err := template.New("plist").Parse(plistTemplate)
-	if err != nil {
-		return err
-	}
-
-	f, err := os.Create(plistPath)
-	if err != nil {
-		return fmt.Errorf("create plist: %w", err)
-	}
-	defer f.Close()
-
-	if err := tmpl.Execute(f, struct {
-		BinaryPath string
-		LogPath    string
-	}{
+	if err := mgr.Install(service.Options{
 		BinaryPath: exe,
 		LogPath:    config.DaemonLog(),
+		ConfigDir:  config.ConfigDir(),
+		DataDir:    config.DataDir(),
 	}); err != nil {
 		return err
 	}
 
-	if err := exec.Command("launchctl", "load", plistPath).Run(); err != nil {
-		return fmt.Errorf("launchctl load failed: %w", err)
-	}
-
-	fmt.Println("Installed and started.")
-	fmt.Printf("  Plist:  %s\n", plistPath)
-	fmt.Printf("  Log:    %s\n", config.DaemonLog())
-	fmt.Printf("  Config: %s\n", config.ConfigPath())
-	fmt.Printf("  Socket: %s\n", config.SocketPath())
+	fmt.Printf("Installed and started (%s).\n", mgr.Name())
+	fmt.Printf("  Service: %s\n", mgr.Path())
+	fmt.Printf("  Log:     %s\n", config.DaemonLog())
+	fmt.Printf("  Config:  %s\n", config.ConfigPath())
+	fmt.Printf("  Socket:  %s\n", config.SocketPath())
 	return nil
 }
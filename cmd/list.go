@@ -1,14 +1,15 @@
 package cmd
 
 import (
-	"encoding/json"
 	"fmt"
-
-	"sc/internal/ipc"
+	"sort"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
 
+var listExpanded bool
+
 var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all blocked domains",
@@ -16,29 +17,42 @@ var listCmd = &cobra.Command{
 }
 
 func init() {
+	listCmd.Flags().BoolVar(&listExpanded, "expanded", false, "show every host name written for each domain")
 	rootCmd.AddCommand(listCmd)
 }
 
 func runList(cmd *cobra.Command, args []string) error {
-	client := newClient()
-	resp, err := client.Send(ipc.Request{Command: ipc.CmdList})
+	ctx, cancel := requestContext(cmd)
+	defer cancel()
+	data, err := newClient().List(ctx, listExpanded)
 	if err != nil {
 		return err
 	}
-	if !resp.OK {
-		return fmt.Errorf("daemon: %s", resp.Error)
-	}
 
-	raw, _ := json.Marshal(resp.Data)
-	var data ipc.ListData
-	json.Unmarshal(raw, &data)
+	if ok, err := printStructured(data); ok {
+		return err
+	}
 
 	if len(data.Domains) == 0 {
 		fmt.Println("No domains configured")
 	} else {
 		for _, d := range data.Domains {
 			fmt.Println(d)
+			if names := data.Expanded[d]; len(names) > 1 {
+				for _, name := range names[1:] {
+					fmt.Printf("  %s\n", name)
+				}
+			}
 		}
 	}
+
+	names := make([]string, 0, len(data.Groups))
+	for name := range data.Groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("\n@%s: %s\n", name, strings.Join(data.Groups[name], ", "))
+	}
 	return nil
 }
@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
 	"text/tabwriter"
 
 	"sc/internal/config"
@@ -37,12 +38,38 @@ func runLogs(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	var events []logs.Entry
+	var tampers, denied []logs.Entry
+	for _, e := range entries {
+		switch e.Event {
+		case "tamper":
+			tampers = append(tampers, e)
+		case "denied":
+			denied = append(denied, e)
+		}
+		if e.Event != "usage" {
+			events = append(events, e)
+		}
+	}
+
+	stats := logs.Stats(entries)
+	usage := logs.DailyUsage(entries)
+
+	if ok, err := printStructured(struct {
+		Stats          []logs.DomainStats `json:"stats"`
+		Usage          []logs.DayUsage    `json:"usage"`
+		TamperAttempts int                `json:"tamper_attempts"`
+		DeniedRequests int                `json:"denied_requests"`
+		Events         []logs.Entry       `json:"events"`
+	}{orEmpty(stats), orEmpty(usage), len(tampers), len(denied), orEmpty(events)}); ok {
+		return err
+	}
+
 	if len(entries) == 0 {
 		fmt.Println("No log entries found")
 		return nil
 	}
 
-	stats := logs.Stats(entries)
 	if len(stats) > 0 {
 		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
 		fmt.Fprintln(w, "DOMAIN\tUNBLOCKS\tTOTAL TIME\tLAST UNBLOCK")
@@ -54,25 +81,85 @@ func runLogs(cmd *cobra.Command, args []string) error {
 		fmt.Println()
 	}
 
-	fmt.Printf("Recent events (%d total):\n", len(entries))
+	if len(usage) > 0 {
+		cfg, err := config.Read(config.ConfigPath())
+		if err != nil {
+			cfg = config.Default()
+		}
+
+		fmt.Println("Budget usage:")
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "  DAY\tDOMAIN\tUSED\tDAILY BUDGET")
+		for _, u := range usage {
+			budget := "-"
+			if daily := cfg.BudgetFor(u.Domain).Daily.Duration; daily > 0 {
+				budget = logs.FormatDuration(daily)
+			}
+			fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n", u.Day, u.Domain, logs.FormatDuration(u.Used), budget)
+		}
+		w.Flush()
+		fmt.Println()
+	}
+
+	if len(tampers) > 0 {
+		last := tampers[len(tampers)-1].Timestamp.Format("2006-01-02 15:04")
+		fmt.Printf("Tamper attempts: %d (last %s)\n\n", len(tampers), last)
+	}
+	if len(denied) > 0 {
+		last := denied[len(denied)-1].Timestamp.Format("2006-01-02 15:04")
+		fmt.Printf("Denied requests: %d (last %s)\n\n", len(denied), last)
+	}
+
+	fmt.Printf("Recent events (%d total):\n", len(events))
 	// Show last 20 entries
 	start := 0
-	if len(entries) > 20 {
-		start = len(entries) - 20
+	if len(events) > 20 {
+		start = len(events) - 20
 	}
-	for _, e := range entries[start:] {
+	for _, e := range events[start:] {
 		ts := e.Timestamp.Format("Jan 02 15:04")
 		switch e.Event {
 		case "unblock":
-			fmt.Printf("  %s  unblock  %-20s  for %s\n", ts, e.Domain, e.Duration)
+			fmt.Printf("  %s  unblock  %-20s  for %s%s\n", ts, e.Domain, e.Duration, requester(e))
 		case "reblock":
 			reason := e.Reason
 			if reason == "" {
 				reason = "manual"
 			}
-			fmt.Printf("  %s  reblock  %-20s  (%s)\n", ts, e.Domain, reason)
+			fmt.Printf("  %s  reblock  %-20s  (%s)%s\n", ts, e.Domain, reason, requester(e))
+		case "focus":
+			fmt.Printf("  %s  focus    %-20s  for %s%s\n", ts, "(all)", e.Duration, requester(e))
+		case "add":
+			fmt.Printf("  %s  add      %-20s%s\n", ts, e.Domain, requester(e))
+		case "remove":
+			fmt.Printf("  %s  remove   %-20s%s\n", ts, e.Domain, requester(e))
+		case "config":
+			fmt.Printf("  %s  config   %-20s  (%s)%s\n", ts, "-", e.Reason, requester(e))
+			for _, c := range e.Changes {
+				fmt.Printf("      %s\n", c)
+			}
+		case "denied":
+			fmt.Printf("  %s  denied   %-20s  (%s)\n", ts, "-", e.Reason)
+			for _, c := range e.Changes {
+				fmt.Printf("      %s\n", c)
+			}
+		case "tamper":
+			fmt.Printf("  %s  tamper   %-20s  (%s)\n", ts, "-", e.Reason)
+			for _, line := range strings.Split(e.Diff, "\n") {
+				if line != "" {
+					fmt.Printf("      %s\n", line)
+				}
+			}
 		}
 	}
 
 	return nil
 }
+
+// requester describes who asked for an event, for entries that record it.
+func requester(e logs.Entry) string {
+	if e.Peer == nil {
+		return ""
+	}
+	return "  by " + e.Peer.String()
+}
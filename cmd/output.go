@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats accepted by --output.
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+var outputFormat string
+
+func init() {
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", outputTable, "output format: table, json, yaml")
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		switch outputFormat {
+		case outputTable, outputJSON, outputYAML:
+			return nil
+		}
+		return fmt.Errorf("invalid --output %q: use table, json or yaml", outputFormat)
+	}
+}
+
+// structured reports whether output should be machine-readable.
+func structured() bool {
+	return outputFormat != outputTable
+}
+
+// printStructured writes v as JSON or YAML when --output asks for it and
+// reports whether it did; with table output the caller prints as usual.
+// YAML uses the same field names as JSON.
+func printStructured(v any) (bool, error) {
+	if !structured() {
+		return false, nil
+	}
+
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return true, err
+	}
+	if outputFormat == outputJSON {
+		_, err = fmt.Println(string(data))
+		return true, err
+	}
+
+	// JSON is valid YAML; decoding it into a node keeps the key order.
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return true, err
+	}
+	clearStyle(&node)
+	out, err := yaml.Marshal(&node)
+	if err != nil {
+		return true, err
+	}
+	_, err = os.Stdout.Write(out)
+	return true, err
+}
+
+func clearStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		clearStyle(c)
+	}
+}
+
+// messages is where prompts and notes go: stdout for tables, stderr when
+// stdout carries JSON or YAML.
+func messages() io.Writer {
+	if structured() {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// orEmpty turns a nil slice into an empty one so it encodes as [] rather
+// than null.
+func orEmpty[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
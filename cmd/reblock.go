@@ -1,17 +1,13 @@
 package cmd
 
 import (
-	"encoding/json"
 	"fmt"
-	"strings"
-
-	"sc/internal/ipc"
 
 	"github.com/spf13/cobra"
 )
 
 var reblockCmd = &cobra.Command{
-	Use:   "reblock [domain...]",
+	Use:   "reblock [domain|@group...]",
 	Short: "Immediately reblock domains (all if none specified)",
 	RunE:  runReblock,
 }
@@ -21,26 +17,16 @@ func init() {
 }
 
 func runReblock(cmd *cobra.Command, args []string) error {
-	reqArgs := map[string]string{}
-	if len(args) > 0 {
-		reqArgs["domains"] = strings.Join(args, ",")
-	}
-
-	client := newClient()
-	resp, err := client.Send(ipc.Request{
-		Command: ipc.CmdReblock,
-		Args:    reqArgs,
-	})
+	ctx, cancel := requestContext(cmd)
+	defer cancel()
+	data, err := newClient().Reblock(ctx, args)
 	if err != nil {
 		return err
 	}
-	if !resp.OK {
-		return fmt.Errorf("daemon: %s", resp.Error)
-	}
 
-	raw, _ := json.Marshal(resp.Data)
-	var data ipc.ReblockData
-	json.Unmarshal(raw, &data)
+	if ok, err := printStructured(data); ok {
+		return err
+	}
 
 	if len(data.Domains) == 0 {
 		fmt.Println("No domains were unblocked")
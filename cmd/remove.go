@@ -1,17 +1,13 @@
 package cmd
 
 import (
-	"encoding/json"
 	"fmt"
-	"strings"
-
-	"sc/internal/ipc"
 
 	"github.com/spf13/cobra"
 )
 
 var removeCmd = &cobra.Command{
-	Use:   "remove <domain...>",
+	Use:   "remove <domain|@group...>",
 	Short: "Remove domains from the block list",
 	Args:  cobra.MinimumNArgs(1),
 	RunE:  runRemove,
@@ -22,21 +18,16 @@ func init() {
 }
 
 func runRemove(cmd *cobra.Command, args []string) error {
-	client := newClient()
-	resp, err := client.Send(ipc.Request{
-		Command: ipc.CmdRemove,
-		Args:    map[string]string{"domains": strings.Join(args, ",")},
-	})
+	ctx, cancel := requestContext(cmd)
+	defer cancel()
+	data, err := newClient().Remove(ctx, args)
 	if err != nil {
 		return err
 	}
-	if !resp.OK {
-		return fmt.Errorf("daemon: %s", resp.Error)
-	}
 
-	raw, _ := json.Marshal(resp.Data)
-	var data ipc.MutateData
-	json.Unmarshal(raw, &data)
+	if ok, err := printStructured(data); ok {
+		return err
+	}
 
 	if len(data.Removed) == 0 {
 		fmt.Println("No matching domains found")
@@ -1,17 +1,21 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"time"
 
 	"sc/internal/config"
-	"sc/internal/ipc"
+	"sc/pkg/scclient"
 
 	"github.com/spf13/cobra"
 )
 
 var version = "dev"
 
+var requestTimeout time.Duration
+
 func SetVersion(v string) {
 	version = v
 }
@@ -24,8 +28,12 @@ var rootCmd = &cobra.Command{
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Print version",
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if ok, err := printStructured(map[string]string{"version": version}); ok {
+			return err
+		}
 		fmt.Println("sc", version)
+		return nil
 	},
 }
 
@@ -36,9 +44,16 @@ func Execute() {
 }
 
 func init() {
+	rootCmd.PersistentFlags().DurationVar(&requestTimeout, "timeout", 5*time.Second, "how long to wait for the daemon")
 	rootCmd.AddCommand(versionCmd)
 }
 
-func newClient() *ipc.Client {
-	return ipc.NewClient(config.SocketPath())
+func newClient() *scclient.Client {
+	return scclient.New(config.SocketPath())
+}
+
+// requestContext bounds a request to the daemon by --timeout.
+func requestContext(cmd *cobra.Command) (context.Context, context.CancelFunc) {
+	return context.WithTimeoutCause(cmd.Context(), requestTimeout,
+		fmt.Errorf("daemon did not respond within %s", requestTimeout))
 }
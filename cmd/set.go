@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"fmt"
+
+	"sc/internal/config"
+
+	"github.com/spf13/cobra"
+)
+
+var setCmd = &cobra.Command{
+	Use:       "set <key> <value>",
+	Short:     "Change a setting in the running daemon and the config file",
+	Long:      "Change a single config setting. The value is parsed like the config file (durations such as 30m, true/false, comma-separated lists), validated, applied immediately and saved. See sc get --help for the keys.",
+	Args:      cobra.ExactArgs(2),
+	ValidArgs: config.Keys(),
+	RunE:      runSet,
+}
+
+func init() {
+	rootCmd.AddCommand(setCmd)
+}
+
+func runSet(cmd *cobra.Command, args []string) error {
+	ctx, cancel := requestContext(cmd)
+	defer cancel()
+	data, err := newClient().Set(ctx, args[0], args[1])
+	if err != nil {
+		return err
+	}
+
+	if ok, err := printStructured(data); ok {
+		return err
+	}
+
+	if len(data.Changes) == 0 {
+		fmt.Printf("%s is already %s\n", data.Key, data.Value)
+		return nil
+	}
+	fmt.Printf("%s = %s\n", data.Key, data.Value)
+	return nil
+}
@@ -1,16 +1,20 @@
 package cmd
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 	"text/tabwriter"
+	"time"
 
-	"sc/internal/ipc"
+	"sc/internal/config"
+	"sc/pkg/scclient"
 
 	"github.com/spf13/cobra"
 )
 
+var statusGroup string
+
 var statusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Show blocked/unblocked status of all domains",
@@ -18,40 +22,102 @@ var statusCmd = &cobra.Command{
 }
 
 func init() {
+	statusCmd.Flags().StringVar(&statusGroup, "group", "", "only show domains in this group")
 	rootCmd.AddCommand(statusCmd)
 }
 
 func runStatus(cmd *cobra.Command, args []string) error {
-	client := newClient()
-	resp, err := client.Send(ipc.Request{Command: ipc.CmdStatus})
+	ctx, cancel := requestContext(cmd)
+	defer cancel()
+	data, err := newClient().Status(ctx)
 	if err != nil {
 		return err
 	}
-	if !resp.OK {
-		return fmt.Errorf("daemon: %s", resp.Error)
-	}
 
-	raw, _ := json.Marshal(resp.Data)
-	var data ipc.StatusData
-	json.Unmarshal(raw, &data)
+	if statusGroup != "" {
+		data.Domains = filterGroup(data.Domains, strings.TrimPrefix(statusGroup, config.GroupPrefix))
+	}
+	if ok, err := printStructured(data); ok {
+		return err
+	}
 
 	fmt.Printf("Uptime: %s\n\n", data.Uptime)
 
+	if data.FocusUntil != "" {
+		until := data.FocusUntil
+		if t, err := time.Parse(time.RFC3339, data.FocusUntil); err == nil {
+			until = t.Format("Mon 15:04")
+		}
+		fmt.Printf("*** FOCUS SESSION ACTIVE — %s left (until %s) ***\n\n", data.FocusRemaining, until)
+	}
+
+	if statusGroup != "" && len(data.Domains) == 0 {
+		fmt.Printf("No domains in group %s\n", statusGroup)
+		return nil
+	}
+
 	if len(data.Domains) == 0 {
 		fmt.Println("No domains configured. Use: sc add <domain>")
 		return nil
 	}
 
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	fmt.Fprintln(w, "DOMAIN\tSTATE\tREMAINING")
+	fmt.Fprintln(w, "DOMAIN\tSTATE\tREMAINING\tBUDGET LEFT\tSCHEDULE\tNEXT")
 	for _, d := range data.Domains {
-		remaining := "-"
-		if d.Remaining != "" {
-			remaining = d.Remaining
+		state := d.State
+		if d.Cooldown != "" {
+			state += fmt.Sprintf(" (cooldown %s)", d.Cooldown)
 		}
-		fmt.Fprintf(w, "%s\t%s\t%s\n", d.Domain, d.State, remaining)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			d.Domain, state, orDash(d.Remaining), orDash(d.BudgetRemaining),
+			orDash(d.Schedule), formatTransition(d))
 	}
 	w.Flush()
 
 	return nil
 }
+
+func formatTransition(d scclient.StatusEntry) string {
+	if d.NextTransition == "" {
+		return "-"
+	}
+	t, err := time.Parse(time.RFC3339, d.NextTransition)
+	if err != nil {
+		return d.NextTransition
+	}
+
+	when := t.Format("Mon 15:04")
+	if sameDay(t, time.Now()) {
+		when = t.Format("15:04")
+	}
+	if d.ScheduleState == "allowed" {
+		return "blocks at " + when
+	}
+	return "allowed at " + when
+}
+
+func filterGroup(entries []scclient.StatusEntry, group string) []scclient.StatusEntry {
+	var result []scclient.StatusEntry
+	for _, e := range entries {
+		for _, g := range e.Groups {
+			if g == group {
+				result = append(result, e)
+				break
+			}
+		}
+	}
+	return result
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
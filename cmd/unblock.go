@@ -2,14 +2,12 @@ package cmd
 
 import (
 	"bufio"
-	"encoding/json"
 	"fmt"
 	"os"
 	"strings"
 	"time"
 
 	"sc/internal/config"
-	"sc/internal/ipc"
 
 	"github.com/spf13/cobra"
 )
@@ -17,9 +15,9 @@ import (
 var skipConfirm bool
 
 var unblockCmd = &cobra.Command{
-	Use:   "unblock [domain...] [duration]",
+	Use:   "unblock [domain|@group...] [duration]",
 	Short: "Temporarily unblock domains (all if none specified)",
-	Long:  "Temporarily unblock one or more domains. No args unblocks all. Last argument is parsed as duration (e.g. 15m, 1h). If omitted, uses default_duration from config.",
+	Long:  "Temporarily unblock one or more domains or @groups. No args unblocks all. Last argument is parsed as duration (e.g. 15m, 1h). If omitted, uses default_duration from config (or the group's).",
 	RunE:  runUnblock,
 }
 
@@ -42,35 +40,44 @@ func runUnblock(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	cfg, err := config.Load(config.ConfigPath())
+	cfg, err := config.Read(config.ConfigPath())
 	if err != nil {
 		cfg = config.Default()
 	}
 
+	targets, err := cfg.ExpandTargets(domains)
+	if err != nil {
+		return err
+	}
+	if len(targets) == 0 {
+		targets = cfg.Domains
+	}
+
 	if duration == "" {
-		duration = cfg.Settings.DefaultDuration.Duration.String()
+		duration = cfg.DefaultDurationFor(targets).String()
 	}
 
 	// Enforce max unblock duration
-	if cfg.Settings.MaxUnblockDuration.Duration > 0 {
+	if max := cfg.MaxUnblockFor(targets); max > 0 {
 		dur, _ := time.ParseDuration(duration)
-		if dur > cfg.Settings.MaxUnblockDuration.Duration {
-			fmt.Printf("Requested duration %s exceeds max allowed %s, capping.\n",
-				duration, cfg.Settings.MaxUnblockDuration.Duration)
-			duration = cfg.Settings.MaxUnblockDuration.Duration.String()
+		if dur > max {
+			fmt.Fprintf(messages(), "Requested duration %s exceeds max allowed %s, capping.\n", duration, max)
+			duration = max.String()
 		}
 	}
 
 	// Step through each warning, require confirmation for each
-	if !skipConfirm && len(cfg.Settings.UnblockWarnings) > 0 {
+	warnings := cfg.WarningsFor(targets)
+	if !skipConfirm && len(warnings) > 0 {
 		reader := bufio.NewReader(os.Stdin)
-		fmt.Println()
-		for _, w := range cfg.Settings.UnblockWarnings {
-			fmt.Printf("  %s [y/N] ", w)
+		out := messages()
+		fmt.Fprintln(out)
+		for _, w := range warnings {
+			fmt.Fprintf(out, "  %s [y/N] ", w)
 			answer, _ := reader.ReadString('\n')
 			answer = strings.TrimSpace(strings.ToLower(answer))
 			if answer != "y" && answer != "yes" {
-				fmt.Println("Cancelled.")
+				fmt.Fprintln(out, "Cancelled.")
 				return nil
 			}
 		}
@@ -79,34 +86,30 @@ func runUnblock(cmd *cobra.Command, args []string) error {
 		if len(domains) > 0 {
 			target = strings.Join(domains, ", ")
 		}
-		fmt.Printf("\n  Unblock %s for %s? [y/N] ", target, duration)
+		fmt.Fprintf(out, "\n  Unblock %s for %s? [y/N] ", target, duration)
 		answer, _ := reader.ReadString('\n')
 		answer = strings.TrimSpace(strings.ToLower(answer))
 		if answer != "y" && answer != "yes" {
-			fmt.Println("Cancelled.")
+			fmt.Fprintln(out, "Cancelled.")
 			return nil
 		}
 	}
 
-	client := newClient()
-	resp, err := client.Send(ipc.Request{
-		Command: ipc.CmdUnblock,
-		Args: map[string]string{
-			"domains":  strings.Join(domains, ","),
-			"duration": duration,
-		},
-	})
+	dur, _ := time.ParseDuration(duration)
+	ctx, cancel := requestContext(cmd)
+	defer cancel()
+	data, err := newClient().Unblock(ctx, domains, dur)
 	if err != nil {
 		return err
 	}
-	if !resp.OK {
-		return fmt.Errorf("daemon: %s", resp.Error)
-	}
 
-	raw, _ := json.Marshal(resp.Data)
-	var data ipc.UnblockData
-	json.Unmarshal(raw, &data)
+	if ok, err := printStructured(data); ok {
+		return err
+	}
 
+	if data.BudgetLimited {
+		fmt.Printf("Duration capped to %s by remaining budget.\n", data.Duration)
+	}
 	for _, d := range data.Domains {
 		fmt.Printf("Unblocked %s for %s\n", d, data.Duration)
 	}
@@ -6,13 +6,15 @@ import (
 	"os/exec"
 
 	"sc/internal/hosts"
+	"sc/internal/nft"
+	"sc/internal/service"
 
 	"github.com/spf13/cobra"
 )
 
 var uninstallCmd = &cobra.Command{
 	Use:   "uninstall",
-	Short: "Remove launchd daemon (requires sudo)",
+	Short: "Remove the daemon service (requires sudo)",
 	RunE:  runUninstall,
 }
 
@@ -25,16 +27,25 @@ func runUninstall(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("uninstall requires root — run: sudo sc uninstall")
 	}
 
-	_ = exec.Command("launchctl", "unload", plistPath).Run()
+	mgr, err := service.New()
+	if err != nil {
+		return err
+	}
 
-	if err := os.Remove(plistPath); err != nil && !os.IsNotExist(err) {
-		return fmt.Errorf("remove plist: %w", err)
+	if err := mgr.Uninstall(); err != nil {
+		return err
 	}
 
 	if err := hosts.Remove(); err != nil {
 		fmt.Printf("Warning: failed to clean /etc/hosts: %v\n", err)
 	}
 
-	fmt.Println("Uninstalled. Daemon stopped, plist removed, /etc/hosts cleaned.")
+	if _, err := exec.LookPath("nft"); err == nil {
+		if err := nft.Remove(nft.ExecRunner{}); err != nil {
+			fmt.Printf("Warning: failed to remove nftables rules: %v\n", err)
+		}
+	}
+
+	fmt.Printf("Uninstalled. Daemon stopped, %s removed, /etc/hosts cleaned.\n", mgr.Path())
 	return nil
 }
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"sc/pkg/scclient"
+
+	"github.com/spf13/cobra"
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Stream daemon events as they happen",
+	Long: `Print unblocks, reblocks, expiry warnings, domain changes, tampering,
+config reloads and focus sessions as the daemon reports them. With
+--output json each event is one line of JSON; with yaml each is a document.`,
+	Args: cobra.NoArgs,
+	RunE: runWatch,
+}
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+}
+
+func runWatch(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	sub, err := newClient().Subscribe(ctx)
+	if err != nil {
+		return err
+	}
+	defer sub.Close()
+
+	fmt.Fprintln(messages(), "Watching daemon events (Ctrl-C to stop)")
+	for {
+		ev, err := sub.Next()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			if errors.Is(err, io.EOF) {
+				return fmt.Errorf("daemon closed the event stream")
+			}
+			return err
+		}
+		if err := printEvent(ev); err != nil {
+			return err
+		}
+	}
+}
+
+// printEvent writes one event: a line of text for tables, compact JSON per
+// line, or a YAML document per event so the stream stays parseable.
+func printEvent(ev scclient.Event) error {
+	switch outputFormat {
+	case outputJSON:
+		data, err := json.Marshal(ev)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Println(string(data))
+		return err
+	case outputYAML:
+		fmt.Println("---")
+		_, err := printStructured(ev)
+		return err
+	}
+
+	ts := ev.Time
+	if t, err := time.Parse(time.RFC3339, ev.Time); err == nil {
+		ts = t.Local().Format("15:04:05")
+	}
+	fmt.Printf("%s  %s\n", ts, describeEvent(ev))
+	return nil
+}
+
+func describeEvent(ev scclient.Event) string {
+	domains := strings.Join(ev.Domains, ", ")
+	switch ev.Type {
+	case scclient.EventUnblocked:
+		return fmt.Sprintf("Unblocked %s for %s", domains, ev.Duration)
+	case scclient.EventReblocked:
+		if ev.Reason != "" {
+			return fmt.Sprintf("Reblocked %s (%s)", domains, ev.Reason)
+		}
+		return fmt.Sprintf("Reblocked %s", domains)
+	case scclient.EventExpiring:
+		return fmt.Sprintf("%s reblocks in %s", domains, ev.Duration)
+	case scclient.EventDomainAdded:
+		if ev.Reason != "" {
+			return fmt.Sprintf("Added %s to @%s", domains, ev.Reason)
+		}
+		return fmt.Sprintf("Added %s", domains)
+	case scclient.EventDomainRemoved:
+		return fmt.Sprintf("Removed %s", domains)
+	case scclient.EventTamper:
+		return fmt.Sprintf("Tampering detected: %s", ev.Reason)
+	case scclient.EventConfigReloaded:
+		s := fmt.Sprintf("Config reloaded (%s)", ev.Reason)
+		for _, c := range ev.Changes {
+			s += "\n          " + c
+		}
+		return s
+	case scclient.EventConfigRefused:
+		s := fmt.Sprintf("Config change refused (%s)", ev.Reason)
+		for _, c := range ev.Changes {
+			s += "\n          " + c
+		}
+		return s
+	case scclient.EventFocusStarted:
+		return fmt.Sprintf("Focus mode started for %s", ev.Duration)
+	}
+	return ev.Type
+}
@@ -0,0 +1,91 @@
+package blocker
+
+import "strings"
+
+// Entry is one blocked domain and every host name it expands to.
+type Entry struct {
+	Domain string
+	Hosts  []string
+}
+
+// Blocker enforces a block list with one mechanism (hosts file, DNS proxy,
+// firewall rules, ...). Apply is called whenever the block list may need
+// re-applying and must be idempotent.
+type Blocker interface {
+	Name() string
+	// Apply makes entries the complete set of blocked names and reports
+	// whether anything had to change.
+	Apply(entries []Entry) (bool, error)
+	// Remove lifts every block this blocker installed.
+	Remove() error
+	// Verify checks that entries are currently being enforced.
+	Verify(entries []Entry) error
+}
+
+// Service is implemented by blockers that run in the background and must be
+// started before the first Apply.
+type Service interface {
+	Start() error
+	Stop()
+}
+
+// Tamper describes a change to a blocker's enforcement made by someone
+// other than sc, noticed while re-applying the block list.
+type Tamper struct {
+	Detail string
+	Diff   string
+}
+
+// TamperDetector is implemented by blockers that can tell their own writes
+// apart from outside changes. Tampers returns what the last Apply found.
+type TamperDetector interface {
+	Tampers() []Tamper
+}
+
+// Watched is implemented by blockers that enforce through files on disk.
+// The daemon watches Files and re-applies as soon as one of them changes.
+type Watched interface {
+	Files() []string
+}
+
+// ExpandOptions controls which host names each blocked domain expands to.
+type ExpandOptions struct {
+	// BlockSubdomains adds www. and the built-in catalog entries.
+	BlockSubdomains bool
+	// Subdomains holds extra labels or full host names per domain.
+	Subdomains map[string][]string
+}
+
+// Expand returns every host name blocked for domain, the domain itself first.
+func Expand(domain string, opts ExpandOptions) []string {
+	names := []string{domain}
+	seen := map[string]bool{domain: true}
+	add := func(entries []string) {
+		for _, e := range entries {
+			name := e
+			if !strings.Contains(e, ".") {
+				name = e + "." + domain
+			}
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+
+	if opts.BlockSubdomains {
+		add([]string{"www"})
+		add(catalog[domain])
+	}
+	add(opts.Subdomains[domain])
+	return names
+}
+
+// Names flattens entries into the full list of blocked host names.
+func Names(entries []Entry) []string {
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Hosts...)
+	}
+	return names
+}
@@ -0,0 +1,51 @@
+package blocker
+
+// catalog lists well-known extra host names for popular sites. Entries
+// without a dot are subdomain labels of the site; entries with a dot are
+// full host names, typically short links or CDN/embed domains.
+var catalog = map[string][]string{
+	"youtube.com": {
+		"m", "music", "gaming", "kids",
+		"youtu.be", "youtube-nocookie.com", "www.youtube-nocookie.com", "youtubekids.com", "www.youtubekids.com",
+	},
+	"reddit.com": {
+		"old", "new", "np", "m", "i", "amp", "out", "gateway",
+		"redd.it", "i.redd.it", "v.redd.it", "preview.redd.it", "external-preview.redd.it",
+		"redditmedia.com", "www.redditmedia.com", "redditstatic.com", "www.redditstatic.com",
+	},
+	"x.com": {
+		"mobile", "api",
+		"twitter.com", "www.twitter.com", "mobile.twitter.com", "api.twitter.com", "t.co", "twimg.com", "pbs.twimg.com", "abs.twimg.com", "video.twimg.com",
+	},
+	"twitter.com": {
+		"mobile", "api",
+		"x.com", "www.x.com", "mobile.x.com", "t.co", "twimg.com", "pbs.twimg.com", "abs.twimg.com", "video.twimg.com",
+	},
+	"facebook.com": {
+		"m", "mobile", "web", "touch", "mbasic",
+		"fb.com", "www.fb.com", "fb.me", "fbcdn.net", "static.xx.fbcdn.net", "messenger.com", "www.messenger.com",
+	},
+	"instagram.com": {
+		"m", "help", "about",
+		"cdninstagram.com", "static.cdninstagram.com", "instagr.am", "www.instagr.am",
+	},
+	"linkedin.com": {
+		"m", "touch",
+		"lnkd.in", "licdn.com", "static.licdn.com", "media.licdn.com",
+	},
+	"netflix.com": {
+		"m", "help", "assets",
+		"nflxvideo.net", "nflximg.net", "nflxext.com", "nflxso.net",
+	},
+	"tiktok.com": {
+		"m", "vm", "vt",
+		"tiktokv.com", "tiktokcdn.com", "tiktokcdn-us.com", "byteoversea.com",
+	},
+	"twitch.tv": {
+		"m", "clips", "player", "gql", "usher",
+		"ttvnw.net", "jtvnw.net", "static-cdn.jtvnw.net",
+	},
+	"news.ycombinator.com": {
+		"hn.algolia.com",
+	},
+}
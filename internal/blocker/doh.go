@@ -0,0 +1,49 @@
+package blocker
+
+// FirefoxCanary is the domain Firefox resolves before enabling DNS over
+// HTTPS by default. If it does not resolve, Firefox leaves DoH off.
+const FirefoxCanary = "use-application-dns.net"
+
+// DoHEndpoints are well-known public DNS-over-HTTPS servers. Browsers using
+// them resolve names without consulting the hosts file or local resolver.
+var DoHEndpoints = []string{
+	"dns.google",
+	"dns.google.com",
+	"dns64.dns.google",
+	"cloudflare-dns.com",
+	"mozilla.cloudflare-dns.com",
+	"chrome.cloudflare-dns.com",
+	"one.one.one.one",
+	"1dot1dot1dot1.cloudflare-dns.com",
+	"dns.quad9.net",
+	"dns9.quad9.net",
+	"dns10.quad9.net",
+	"dns11.quad9.net",
+	"doh.opendns.com",
+	"doh.familyshield.opendns.com",
+	"dns.nextdns.io",
+	"firefox.dns.nextdns.io",
+	"doh.cleanbrowsing.org",
+	"dns.adguard.com",
+	"dns.adguard-dns.com",
+	"doh.dns.sb",
+	"dns.alidns.com",
+	"doh.pub",
+	"doh.mullvad.net",
+	"dns.mullvad.net",
+	"dns.controld.com",
+	"freedns.controld.com",
+	"doh.xfinity.com",
+	"private.canadianshield.cira.ca",
+	"doh.libredns.gr",
+}
+
+// DoHEntries returns the protection set: every DoH endpoint plus the Firefox
+// canary domain, each as its own entry.
+func DoHEntries() []Entry {
+	entries := make([]Entry, 0, len(DoHEndpoints)+1)
+	for _, host := range DoHEndpoints {
+		entries = append(entries, Entry{Domain: host, Hosts: []string{host}})
+	}
+	return append(entries, Entry{Domain: FirefoxCanary, Hosts: []string{FirefoxCanary}})
+}
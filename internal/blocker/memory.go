@@ -0,0 +1,58 @@
+package blocker
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Memory is a Blocker that only records what it was asked to block. It lets
+// the daemon run without touching the system.
+type Memory struct {
+	mu      sync.Mutex
+	entries []Entry
+}
+
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+func (m *Memory) Name() string { return "memory" }
+
+func (m *Memory) Apply(entries []Entry) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if reflect.DeepEqual(m.entries, entries) {
+		return false, nil
+	}
+	m.entries = append([]Entry(nil), entries...)
+	return true, nil
+}
+
+func (m *Memory) Remove() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries = nil
+	return nil
+}
+
+func (m *Memory) Verify(entries []Entry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.entries) == 0 && len(entries) == 0 {
+		return nil
+	}
+	if !reflect.DeepEqual(m.entries, entries) {
+		return fmt.Errorf("memory blocker has %d entries, want %d", len(m.entries), len(entries))
+	}
+	return nil
+}
+
+// Entries returns a copy of the currently blocked entries.
+func (m *Memory) Entries() []Entry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Entry(nil), m.entries...)
+}
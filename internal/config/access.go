@@ -0,0 +1,138 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+)
+
+// Actions that access rules control. Every request to the daemon falls
+// under one of them.
+const (
+	ActionRead      = "read"      // status, list, get, watch
+	ActionBlock     = "block"     // add, reblock, focus
+	ActionUnblock   = "unblock"   // unblock
+	ActionRemove    = "remove"    // remove
+	ActionConfigure = "configure" // set, config edit, config reload
+)
+
+// Access limits which local users may send each kind of request to the
+// daemon. A rule with no users or groups lets everyone through; root is
+// always allowed.
+type Access struct {
+	Read      AccessRule `yaml:"read,omitempty"`
+	Block     AccessRule `yaml:"block,omitempty"`
+	Unblock   AccessRule `yaml:"unblock,omitempty"`
+	Remove    AccessRule `yaml:"remove,omitempty"`
+	Configure AccessRule `yaml:"configure,omitempty"`
+}
+
+// AccessRule lists the user and group names (or numeric IDs) allowed.
+type AccessRule struct {
+	Users  []string `yaml:"users,omitempty"`
+	Groups []string `yaml:"groups,omitempty"`
+}
+
+// Rule returns the rule for action.
+func (a Access) Rule(action string) AccessRule {
+	switch action {
+	case ActionRead:
+		return a.Read
+	case ActionBlock:
+		return a.Block
+	case ActionUnblock:
+		return a.Unblock
+	case ActionRemove:
+		return a.Remove
+	case ActionConfigure:
+		return a.Configure
+	}
+	return AccessRule{}
+}
+
+func (a Access) rules() map[string]AccessRule {
+	return map[string]AccessRule{
+		ActionRead:      a.Read,
+		ActionBlock:     a.Block,
+		ActionUnblock:   a.Unblock,
+		ActionRemove:    a.Remove,
+		ActionConfigure: a.Configure,
+	}
+}
+
+// Equal reports whether a and b hold the same rules.
+func (a Access) Equal(b Access) bool {
+	for action, r := range a.rules() {
+		o := b.Rule(action)
+		if !slices.Equal(r.Users, o.Users) || !slices.Equal(r.Groups, o.Groups) {
+			return false
+		}
+	}
+	return true
+}
+
+// Open reports whether the rule lets everyone through.
+func (r AccessRule) Open() bool {
+	return len(r.Users) == 0 && len(r.Groups) == 0
+}
+
+// Allows reports whether a caller matches the rule, given its UID, user name
+// and groups by GID.
+func (r AccessRule) Allows(uid int, user string, groups map[int]string) bool {
+	if uid == 0 || r.Open() {
+		return true
+	}
+	for _, u := range r.Users {
+		if u == user || u == strconv.Itoa(uid) {
+			return true
+		}
+	}
+	for _, g := range r.Groups {
+		for gid, name := range groups {
+			if g == name || g == strconv.Itoa(gid) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (a Access) validate(v *validator) {
+	for _, action := range sortedKeys(a.rules()) {
+		r := a.Rule(action)
+		for i, u := range r.Users {
+			if u == "" {
+				v.addf(fmt.Sprintf("access.%s.users[%d]", action, i), "must not be empty")
+			}
+		}
+		for i, g := range r.Groups {
+			if g == "" {
+				v.addf(fmt.Sprintf("access.%s.groups[%d]", action, i), "must not be empty")
+			}
+		}
+	}
+}
+
+// looserAccess reports rules that let in anyone who was kept out before.
+func looserAccess(old, new Access, add func(string, ...any)) {
+	for _, action := range sortedKeys(old.rules()) {
+		o, n := old.Rule(action), new.Rule(action)
+		if o.Open() {
+			continue
+		}
+		if n.Open() {
+			add("access.%s: restriction removed", action)
+			continue
+		}
+		for _, u := range n.Users {
+			if !contains(o.Users, u) {
+				add("access.%s.users: %s added", action, u)
+			}
+		}
+		for _, g := range n.Groups {
+			if !contains(o.Groups, g) {
+				add("access.%s.groups: %s added", action, g)
+			}
+		}
+	}
+}
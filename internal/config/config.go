@@ -4,9 +4,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
 	"time"
 
+	"sc/internal/schedule"
+
 	"gopkg.in/yaml.v3"
 )
 
@@ -24,18 +25,93 @@ func (d Duration) MarshalText() ([]byte, error) {
 	return []byte(d.Duration.String()), nil
 }
 
+// DNSProxy configures the embedded resolver used by the "dns" backend.
+// Empty upstreams are read from /etc/resolv.conf.
+type DNSProxy struct {
+	Listen    string   `yaml:"listen,omitempty"`
+	Upstreams []string `yaml:"upstreams,omitempty"`
+}
+
+// HTTPAPI configures the optional HTTP/JSON API. Listen is a loopback
+// host:port or "unix:" followed by a socket path; empty disables the API.
+// Requests must present the token stored in TokenFile, which the daemon
+// creates if it does not exist.
+type HTTPAPI struct {
+	Listen    string `yaml:"listen,omitempty"`
+	TokenFile string `yaml:"token_file,omitempty"`
+}
+
+// TokenPath returns TokenFile, or the default location when it is unset.
+func (a HTTPAPI) TokenPath() string {
+	if a.TokenFile != "" {
+		return a.TokenFile
+	}
+	return HTTPTokenPath()
+}
+
+// NFTables configures the Linux firewall backend. IPRanges lists extra
+// CIDRs or addresses blocked together with a domain.
+type NFTables struct {
+	RefreshInterval Duration            `yaml:"refresh_interval,omitempty"`
+	IPRanges        map[string][]string `yaml:"ip_ranges,omitempty"`
+}
+
 type Settings struct {
 	DefaultDuration    Duration `yaml:"default_duration"`
 	MaxUnblockDuration Duration `yaml:"max_unblock_duration,omitempty"`
 	CheckInterval      Duration `yaml:"check_interval"`
 	FlushDNS           bool     `yaml:"flush_dns"`
+	DNSFlushers        []string `yaml:"dns_flushers,omitempty"`
+	DisabledFlushers   []string `yaml:"disabled_dns_flushers,omitempty"`
 	BlockSubdomains    bool     `yaml:"block_subdomains"`
+	Backends           []string `yaml:"backends,omitempty"`
+	DNSProxy           DNSProxy `yaml:"dns_proxy,omitempty"`
+	NFTables           NFTables `yaml:"nftables,omitempty"`
+	BlockDoH           bool     `yaml:"block_doh,omitempty"`
 	UnblockWarnings    []string `yaml:"unblock_warnings,omitempty"`
+	HTTPAPI            HTTPAPI  `yaml:"http_api,omitempty"`
+}
+
+// Budget limits how much unblocked time a domain may use. Zero means no limit.
+type Budget struct {
+	Daily  Duration `yaml:"daily,omitempty"`
+	Weekly Duration `yaml:"weekly,omitempty"`
+}
+
+func (b Budget) IsZero() bool {
+	return b.Daily.Duration == 0 && b.Weekly.Duration == 0
+}
+
+// Budgets holds a default budget applied to every domain, plus per-domain
+// overrides.
+type Budgets struct {
+	Default Budget            `yaml:"default,omitempty"`
+	Domains map[string]Budget `yaml:"domains,omitempty"`
+}
+
+// Cooldowns holds how long a domain must stay blocked after being reblocked
+// before it may be unblocked again, with per-domain overrides.
+type Cooldowns struct {
+	Default Duration            `yaml:"default,omitempty"`
+	Domains map[string]Duration `yaml:"domains,omitempty"`
+}
+
+// DomainSettings holds per-domain options keyed by domain in config.
+type DomainSettings struct {
+	// Subdomains are extra host names to block: bare labels such as "old"
+	// expand to old.<domain>, anything with a dot is used verbatim.
+	Subdomains []string `yaml:"subdomains,omitempty"`
 }
 
 type Config struct {
-	Domains  []string `yaml:"domains"`
-	Settings Settings `yaml:"settings"`
+	Domains        []string                  `yaml:"domains"`
+	DomainSettings map[string]DomainSettings `yaml:"domain_settings,omitempty"`
+	Groups         map[string]Group          `yaml:"groups,omitempty"`
+	Schedules      []schedule.Schedule       `yaml:"schedules,omitempty"`
+	Budgets        Budgets                   `yaml:"budgets,omitempty"`
+	Cooldowns      Cooldowns                 `yaml:"cooldowns,omitempty"`
+	Access         Access                    `yaml:"access,omitempty"`
+	Settings       Settings                  `yaml:"settings"`
 }
 
 func Default() *Config {
@@ -46,6 +122,9 @@ func Default() *Config {
 			CheckInterval:   Duration{5 * time.Second},
 			FlushDNS:        true,
 			BlockSubdomains: true,
+			Backends:        []string{BackendHosts},
+			DNSProxy:        DNSProxy{Listen: "127.0.0.1:53"},
+			NFTables:        NFTables{RefreshInterval: Duration{10 * time.Minute}},
 			UnblockWarnings: []string{
 				"You're about to unblock distracting sites.",
 				"Consider whether this is truly necessary right now.",
@@ -54,14 +133,40 @@ func Default() *Config {
 	}
 }
 
+// Blocking backends selectable in settings.backends.
+const (
+	BackendHosts    = "hosts"
+	BackendDNS      = "dns"
+	BackendNFTables = "nftables"
+)
+
+// UsesBackend reports whether the named blocking backend is enabled.
+func (s Settings) UsesBackend(name string) bool {
+	for _, b := range s.Backends {
+		if b == name {
+			return true
+		}
+	}
+	return false
+}
+
+// dataDir holds the socket, state and logs. Tests that run a daemon move it
+// with SetDataDir.
+var dataDir = "/usr/local/var/sc"
+
+// SetDataDir moves the socket, state, logs and token files to dir.
+func SetDataDir(dir string) { dataDir = dir }
+
 func ConfigDir() string  { return "/usr/local/etc/sc" }
 func ConfigPath() string { return filepath.Join(ConfigDir(), "config.yaml") }
-func DataDir() string    { return "/usr/local/var/sc" }
+func DataDir() string    { return dataDir }
 func SocketPath() string { return filepath.Join(DataDir(), "sc.sock") }
 func StatePath() string  { return filepath.Join(DataDir(), "state.yaml") }
 func LogsPath() string   { return filepath.Join(DataDir(), "logs.jsonl") }
 func DaemonLog() string  { return filepath.Join(DataDir(), "daemon.log") }
 
+func HTTPTokenPath() string { return filepath.Join(DataDir(), "http-token") }
+
 func Load(path string) (*Config, error) {
 	cfg := Default()
 
@@ -76,10 +181,35 @@ func Load(path string) (*Config, error) {
 		return nil, err
 	}
 
+	return Parse(data)
+}
+
+// Read is Load for commands that only look at the config: a missing file
+// yields the defaults and nothing is written.
+func Read(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Default(), nil
+		}
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse decodes and validates config file contents, filling in defaults
+// for anything left out.
+func Parse(data []byte) (*Config, error) {
+	cfg := Default()
 	if err := yaml.Unmarshal(data, cfg); err != nil {
-		return nil, fmt.Errorf("parsing config: %w", err)
+		return nil, &SyntaxError{Err: err}
 	}
 
+	cfg.syncGroupDomains()
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
 	return cfg, nil
 }
 
@@ -92,7 +222,11 @@ func Save(cfg *Config, path string) error {
 	if err != nil {
 		return err
 	}
+	return WriteFile(path, data)
+}
 
+// WriteFile atomically replaces the config file at path with data.
+func WriteFile(path string, data []byte) error {
 	tmp := path + ".tmp"
 	if err := os.WriteFile(tmp, data, 0644); err != nil {
 		return err
@@ -101,7 +235,7 @@ func Save(cfg *Config, path string) error {
 }
 
 func (c *Config) HasDomain(domain string) bool {
-	domain = normalizeDomain(domain)
+	domain = NormalizeDomain(domain)
 	for _, d := range c.Domains {
 		if d == domain {
 			return true
@@ -111,7 +245,7 @@ func (c *Config) HasDomain(domain string) bool {
 }
 
 func (c *Config) AddDomain(domain string) bool {
-	domain = normalizeDomain(domain)
+	domain = NormalizeDomain(domain)
 	if c.HasDomain(domain) {
 		return false
 	}
@@ -120,16 +254,48 @@ func (c *Config) AddDomain(domain string) bool {
 }
 
 func (c *Config) RemoveDomain(domain string) bool {
-	domain = normalizeDomain(domain)
+	domain = NormalizeDomain(domain)
 	for i, d := range c.Domains {
 		if d == domain {
 			c.Domains = append(c.Domains[:i], c.Domains[i+1:]...)
+			c.removeFromGroups(domain)
 			return true
 		}
 	}
 	return false
 }
 
-func normalizeDomain(d string) string {
-	return strings.ToLower(strings.TrimSpace(d))
+// BudgetFor returns the budget governing domain on its own: its entry if
+// present, otherwise the default. Group budgets are shared by all members
+// and are looked up separately.
+func (c *Config) BudgetFor(domain string) Budget {
+	domain = NormalizeDomain(domain)
+	for d, b := range c.Budgets.Domains {
+		if NormalizeDomain(d) == domain {
+			return b
+		}
+	}
+	return c.Budgets.Default
+}
+
+// CooldownFor returns the cooldown governing domain.
+func (c *Config) CooldownFor(domain string) time.Duration {
+	domain = NormalizeDomain(domain)
+	for d, cd := range c.Cooldowns.Domains {
+		if NormalizeDomain(d) == domain {
+			return cd.Duration
+		}
+	}
+	return c.Cooldowns.Default.Duration
+}
+
+// Subdomains returns the configured extra host names per domain.
+func (c *Config) Subdomains() map[string][]string {
+	result := make(map[string][]string, len(c.DomainSettings))
+	for d, ds := range c.DomainSettings {
+		if len(ds.Subdomains) > 0 {
+			result[NormalizeDomain(d)] = ds.Subdomains
+		}
+	}
+	return result
 }
@@ -0,0 +1,128 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Diff describes what differs between two configs, one line per changed
+// setting, e.g. "settings.check_interval: 5s -> 10s" or
+// "domains: +reddit.com -news.com". It returns nil if they are equivalent.
+func Diff(old, new *Config) []string {
+	a, b := flatten(old), flatten(new)
+
+	keys := make(map[string]bool, len(a)+len(b))
+	for k := range a {
+		keys[k] = true
+	}
+	for k := range b {
+		keys[k] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	var changes []string
+	for _, key := range sorted {
+		va, oka := a[key]
+		vb, okb := b[key]
+		la, isListA := va.([]string)
+		lb, isListB := vb.([]string)
+
+		switch {
+		case (isListA || !oka) && (isListB || !okb):
+			if line := listDiff(la, lb); line != "" {
+				changes = append(changes, key+": "+line)
+			}
+		case !oka:
+			changes = append(changes, fmt.Sprintf("%s: set to %v", key, vb))
+		case !okb:
+			changes = append(changes, fmt.Sprintf("%s: unset (was %v)", key, va))
+		case fmt.Sprint(va) != fmt.Sprint(vb):
+			changes = append(changes, fmt.Sprintf("%s: %v -> %v", key, va, vb))
+		}
+	}
+	return changes
+}
+
+// listDiff summarises the items added to and removed from a list.
+func listDiff(old, new []string) string {
+	inOld := make(map[string]bool, len(old))
+	for _, v := range old {
+		inOld[v] = true
+	}
+	inNew := make(map[string]bool, len(new))
+	for _, v := range new {
+		inNew[v] = true
+	}
+
+	var parts []string
+	for _, v := range new {
+		if !inOld[v] {
+			parts = append(parts, "+"+v)
+		}
+	}
+	for _, v := range old {
+		if !inNew[v] {
+			parts = append(parts, "-"+v)
+		}
+	}
+	if len(parts) == 0 && strings.Join(old, "\n") != strings.Join(new, "\n") {
+		return "reordered"
+	}
+	return strings.Join(parts, " ")
+}
+
+// flatten maps every leaf of the config's YAML form to its dotted path.
+// Lists of plain values are kept whole as []string so they can be compared
+// item by item; lists of mappings are indexed, e.g. "schedules[0].name".
+func flatten(cfg *Config) map[string]any {
+	out := make(map[string]any)
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return out
+	}
+	var root any
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return out
+	}
+	flattenValue(root, "", out)
+	return out
+}
+
+func flattenValue(v any, path string, out map[string]any) {
+	join := func(key string) string {
+		if path == "" {
+			return key
+		}
+		return path + "." + key
+	}
+
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			flattenValue(child, join(k), out)
+		}
+	case []any:
+		scalars := make([]string, 0, len(v))
+		for _, item := range v {
+			switch item.(type) {
+			case map[string]any, []any:
+				for i, item := range v {
+					flattenValue(item, fmt.Sprintf("%s[%d]", path, i), out)
+				}
+				return
+			}
+			scalars = append(scalars, fmt.Sprint(item))
+		}
+		out[path] = scalars
+	case nil:
+	default:
+		out[path] = fmt.Sprint(v)
+	}
+}
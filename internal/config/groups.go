@@ -0,0 +1,189 @@
+package config
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// GroupPrefix marks a CLI argument as a group name rather than a domain,
+// e.g. "sc unblock @social 10m".
+const GroupPrefix = "@"
+
+// Group is a named set of domains with optional settings that override the
+// global ones for its members. In YAML a group may be written either as a
+// plain list of domains or as a mapping with a domains key.
+type Group struct {
+	Domains            []string `yaml:"domains"`
+	Budget             Budget   `yaml:"budget,omitempty"`
+	DefaultDuration    Duration `yaml:"default_duration,omitempty"`
+	MaxUnblockDuration Duration `yaml:"max_unblock_duration,omitempty"`
+	UnblockWarnings    []string `yaml:"unblock_warnings,omitempty"`
+}
+
+type plainGroup Group
+
+func (g *Group) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.SequenceNode {
+		return node.Decode(&g.Domains)
+	}
+	return node.Decode((*plainGroup)(g))
+}
+
+func (g Group) MarshalYAML() (interface{}, error) {
+	if g.Budget.IsZero() && g.DefaultDuration.Duration == 0 &&
+		g.MaxUnblockDuration.Duration == 0 && len(g.UnblockWarnings) == 0 {
+		return g.Domains, nil
+	}
+	return plainGroup(g), nil
+}
+
+func (g Group) Has(domain string) bool {
+	domain = NormalizeDomain(domain)
+	for _, d := range g.Domains {
+		if NormalizeDomain(d) == domain {
+			return true
+		}
+	}
+	return false
+}
+
+// GroupNames returns the configured group names in sorted order.
+func (c *Config) GroupNames() []string {
+	names := make([]string, 0, len(c.Groups))
+	for name := range c.Groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GroupsOf returns the sorted names of the groups containing domain.
+func (c *Config) GroupsOf(domain string) []string {
+	var names []string
+	for _, name := range c.GroupNames() {
+		if c.Groups[name].Has(domain) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// ExpandTargets replaces "@group" arguments with the group's domains,
+// dropping duplicates while keeping order.
+func (c *Config) ExpandTargets(targets []string) ([]string, error) {
+	var result []string
+	seen := make(map[string]bool)
+	add := func(domain string) {
+		domain = NormalizeDomain(domain)
+		if !seen[domain] {
+			seen[domain] = true
+			result = append(result, domain)
+		}
+	}
+
+	for _, t := range targets {
+		name, isGroup := strings.CutPrefix(strings.TrimSpace(t), GroupPrefix)
+		if !isGroup {
+			add(t)
+			continue
+		}
+		g, ok := c.Groups[name]
+		if !ok {
+			return nil, &NotFoundError{Kind: "group", Name: name}
+		}
+		for _, d := range g.Domains {
+			add(d)
+		}
+	}
+	return result, nil
+}
+
+// AddToGroup adds domain to the named group, creating the group if needed.
+func (c *Config) AddToGroup(name, domain string) bool {
+	g := c.Groups[name]
+	if g.Has(domain) {
+		return false
+	}
+	g.Domains = append(g.Domains, NormalizeDomain(domain))
+	if c.Groups == nil {
+		c.Groups = make(map[string]Group)
+	}
+	c.Groups[name] = g
+	return true
+}
+
+func (c *Config) removeFromGroups(domain string) {
+	for name, g := range c.Groups {
+		for i, d := range g.Domains {
+			if NormalizeDomain(d) == domain {
+				g.Domains = append(g.Domains[:i], g.Domains[i+1:]...)
+				c.Groups[name] = g
+				break
+			}
+		}
+	}
+}
+
+// syncGroupDomains makes sure every group member is also on the block list.
+func (c *Config) syncGroupDomains() {
+	for _, name := range c.GroupNames() {
+		for _, d := range c.Groups[name].Domains {
+			c.AddDomain(d)
+		}
+	}
+}
+
+// DefaultDurationFor returns the unblock duration to use when none is given:
+// the shortest group default among the domains' groups, or the global default.
+func (c *Config) DefaultDurationFor(domains []string) time.Duration {
+	var best time.Duration
+	for _, domain := range domains {
+		for _, name := range c.GroupsOf(domain) {
+			if d := c.Groups[name].DefaultDuration.Duration; d > 0 && (best == 0 || d < best) {
+				best = d
+			}
+		}
+	}
+	if best == 0 {
+		return c.Settings.DefaultDuration.Duration
+	}
+	return best
+}
+
+// MaxUnblockFor returns the strictest max_unblock_duration that applies to
+// any of the domains. Zero means unlimited.
+func (c *Config) MaxUnblockFor(domains []string) time.Duration {
+	best := c.Settings.MaxUnblockDuration.Duration
+	for _, domain := range domains {
+		for _, name := range c.GroupsOf(domain) {
+			if d := c.Groups[name].MaxUnblockDuration.Duration; d > 0 && (best == 0 || d < best) {
+				best = d
+			}
+		}
+	}
+	return best
+}
+
+// WarningsFor returns the global unblock warnings followed by those of every
+// group the domains belong to.
+func (c *Config) WarningsFor(domains []string) []string {
+	warnings := append([]string(nil), c.Settings.UnblockWarnings...)
+	seen := make(map[string]bool)
+	for _, w := range warnings {
+		seen[w] = true
+	}
+	for _, domain := range domains {
+		for _, name := range c.GroupsOf(domain) {
+			for _, w := range c.Groups[name].UnblockWarnings {
+				if !seen[w] {
+					seen[w] = true
+					warnings = append(warnings, w)
+				}
+			}
+		}
+	}
+	return warnings
+}
@@ -0,0 +1,175 @@
+package config
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Keys returns the dotted paths of every single-valued setting that Get and
+// Set accept, e.g. "settings.default_duration" or "budgets.default.daily".
+// Maps such as budgets.domains are edited with sc config edit instead.
+func Keys() []string {
+	var keys []string
+	var walk func(t reflect.Type, prefix string)
+	walk = func(t reflect.Type, prefix string) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name := yamlName(f)
+			if name == "" {
+				continue
+			}
+			path := prefix + name
+			switch {
+			case isLeaf(f.Type):
+				keys = append(keys, path)
+			case f.Type.Kind() == reflect.Struct:
+				walk(f.Type, path+".")
+			}
+		}
+	}
+	walk(reflect.TypeOf(Config{}), "")
+	sort.Strings(keys)
+	return keys
+}
+
+// Get returns the value of the setting at key, formatted the way it is
+// written in the config file. Lists are comma-separated.
+func (c *Config) Get(key string) (string, error) {
+	v, err := lookupKey(reflect.ValueOf(c).Elem(), key)
+	if err != nil {
+		return "", err
+	}
+
+	switch x := v.Interface().(type) {
+	case encoding.TextMarshaler:
+		text, err := x.MarshalText()
+		return string(text), err
+	case []string:
+		return strings.Join(x, ","), nil
+	default:
+		return fmt.Sprint(x), nil
+	}
+}
+
+// Set parses value into the setting at key using the same rules as the
+// config file. Lists are given comma-separated; an empty value clears them.
+// The result is not validated.
+func (c *Config) Set(key, value string) error {
+	v, err := lookupKey(reflect.ValueOf(c).Elem(), key)
+	if err != nil {
+		return err
+	}
+
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		if err := u.UnmarshalText([]byte(value)); err != nil {
+			return &ValueError{Key: key, Message: fmt.Sprintf("invalid value %q: %v", value, err)}
+		}
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return &ValueError{Key: key, Message: fmt.Sprintf("invalid value %q: expected true or false", value)}
+		}
+		v.SetBool(b)
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return &ValueError{Key: key, Message: "cannot be set from the command line"}
+	}
+	return nil
+}
+
+// Clone returns a deep copy of c.
+func (c *Config) Clone() (*Config, error) {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	clone := &Config{}
+	if err := yaml.Unmarshal(data, clone); err != nil {
+		return nil, err
+	}
+	return clone, nil
+}
+
+// NotFoundError is a reference to a group or setting that does not exist.
+type NotFoundError struct {
+	Kind string
+	Name string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("unknown %s %q", e.Kind, e.Name)
+}
+
+// ValueError is a setting that cannot take the value it was given.
+type ValueError struct {
+	Key     string
+	Message string
+}
+
+func (e *ValueError) Error() string {
+	return e.Key + ": " + e.Message
+}
+
+func lookupKey(v reflect.Value, key string) (reflect.Value, error) {
+	for _, part := range strings.Split(key, ".") {
+		if v.Kind() != reflect.Struct {
+			return reflect.Value{}, &NotFoundError{Kind: "setting", Name: key}
+		}
+		found := false
+		for i := 0; i < v.NumField(); i++ {
+			if yamlName(v.Type().Field(i)) == part {
+				v = v.Field(i)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return reflect.Value{}, &NotFoundError{Kind: "setting", Name: key}
+		}
+	}
+	if !isLeaf(v.Type()) {
+		return reflect.Value{}, &ValueError{Key: key, Message: "not a single setting; use sc config edit"}
+	}
+	return v, nil
+}
+
+func yamlName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+// isLeaf reports whether t is a value Get and Set handle directly.
+func isLeaf(t reflect.Type) bool {
+	if reflect.PointerTo(t).Implements(reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool:
+		return true
+	case reflect.Slice:
+		return t.Elem().Kind() == reflect.String
+	}
+	return false
+}
@@ -0,0 +1,151 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// Loosenings lists the changes from old to new that weaken blocking:
+// domains or host names dropped, limits raised or removed, schedules
+// added, access widened, protections switched off. It returns nil if new is at least as
+// strict as old.
+func Loosenings(old, new *Config) []string {
+	var out []string
+	loosenings(old, new, func(_, change string) {
+		out = append(out, change)
+	})
+	return out
+}
+
+// LooseningsByAction is Loosenings keyed by the access action whose rule
+// the change gets around: dropped domains by remove, new or wider schedules
+// and raised budgets or limits by unblock. Everything else falls under
+// configure.
+func LooseningsByAction(old, new *Config) map[string][]string {
+	var out map[string][]string
+	loosenings(old, new, func(action, change string) {
+		if out == nil {
+			out = make(map[string][]string)
+		}
+		out[action] = append(out[action], change)
+	})
+	return out
+}
+
+func loosenings(old, new *Config, report func(action, change string)) {
+	adder := func(action string) func(string, ...any) {
+		return func(format string, args ...any) {
+			report(action, fmt.Sprintf(format, args...))
+		}
+	}
+	remove, unblock, add := adder(ActionRemove), adder(ActionUnblock), adder(ActionConfigure)
+
+	for _, d := range old.Domains {
+		if !new.HasDomain(d) {
+			remove("domains: %s removed", d)
+		}
+	}
+
+	oldSubs, newSubs := old.Subdomains(), new.Subdomains()
+	for _, d := range sortedKeys(oldSubs) {
+		for _, s := range oldSubs[d] {
+			if !contains(newSubs[d], s) {
+				remove("domain_settings.%s.subdomains: %s removed", d, s)
+			}
+		}
+	}
+
+	for _, s := range new.Schedules {
+		found := false
+		for _, o := range old.Schedules {
+			if reflect.DeepEqual(s, o) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			unblock("schedules: %q added or widened", s.Name)
+		}
+	}
+
+	looserBudget("budgets.default", old.Budgets.Default, new.Budgets.Default, unblock)
+	for _, d := range sortedKeys(old.Budgets.Domains) {
+		looserBudget("budgets.domains."+d, old.BudgetFor(d), new.BudgetFor(d), unblock)
+	}
+	if o, n := old.Cooldowns.Default.Duration, new.Cooldowns.Default.Duration; n < o {
+		unblock("cooldowns.default: %s -> %s", o, n)
+	}
+	for _, d := range sortedKeys(old.Cooldowns.Domains) {
+		if o, n := old.CooldownFor(d), new.CooldownFor(d); n < o {
+			unblock("cooldowns.domains.%s: %s -> %s", d, o, n)
+		}
+	}
+	for _, name := range old.GroupNames() {
+		og, ng := old.Groups[name], new.Groups[name]
+		looserBudget("groups."+name+".budget", og.Budget, ng.Budget, unblock)
+		looserLimit("groups."+name+".max_unblock_duration", og.MaxUnblockDuration.Duration, ng.MaxUnblockDuration.Duration, unblock)
+		for _, w := range og.UnblockWarnings {
+			if !contains(ng.UnblockWarnings, w) {
+				add("groups.%s.unblock_warnings: %q removed", name, w)
+			}
+		}
+	}
+
+	looserAccess(old.Access, new.Access, add)
+
+	oldS, newS := old.Settings, new.Settings
+	looserLimit("settings.max_unblock_duration", oldS.MaxUnblockDuration.Duration, newS.MaxUnblockDuration.Duration, unblock)
+	if newS.DefaultDuration.Duration > oldS.DefaultDuration.Duration {
+		unblock("settings.default_duration: %s -> %s", oldS.DefaultDuration.Duration, newS.DefaultDuration.Duration)
+	}
+	if oldS.BlockSubdomains && !newS.BlockSubdomains {
+		add("settings.block_subdomains: turned off")
+	}
+	if oldS.BlockDoH && !newS.BlockDoH {
+		add("settings.block_doh: turned off")
+	}
+	for _, b := range oldS.Backends {
+		if !newS.UsesBackend(b) {
+			add("settings.backends: %s removed", b)
+		}
+	}
+	for _, d := range sortedKeys(oldS.NFTables.IPRanges) {
+		for _, r := range oldS.NFTables.IPRanges[d] {
+			if !contains(newS.NFTables.IPRanges[d], r) {
+				add("settings.nftables.ip_ranges.%s: %s removed", d, r)
+			}
+		}
+	}
+	for _, w := range oldS.UnblockWarnings {
+		if !contains(newS.UnblockWarnings, w) {
+			add("settings.unblock_warnings: %q removed", w)
+		}
+	}
+}
+
+// looserLimit reports a limit raised or removed; zero means unlimited.
+func looserLimit(path string, old, new time.Duration, add func(string, ...any)) {
+	if old <= 0 || (new > 0 && new <= old) {
+		return
+	}
+	if new <= 0 {
+		add("%s: %s -> unlimited", path, old)
+		return
+	}
+	add("%s: %s -> %s", path, old, new)
+}
+
+func looserBudget(path string, old, new Budget, add func(string, ...any)) {
+	looserLimit(path+".daily", old.Daily.Duration, new.Daily.Duration, add)
+	looserLimit(path+".weekly", old.Weekly.Duration, new.Weekly.Duration, add)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,316 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"sc/internal/dns"
+)
+
+// FieldError is a problem with one config value, located by its YAML path,
+// e.g. "settings.check_interval" or "domains[2]".
+type FieldError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (e FieldError) Error() string {
+	return e.Path + ": " + e.Message
+}
+
+// ValidationError lists every problem found in a config.
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Errors) == 1 {
+		return "invalid config: " + e.Errors[0].Error()
+	}
+	lines := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		lines[i] = "  " + fe.Error()
+	}
+	return fmt.Sprintf("invalid config (%d problems):\n%s", len(e.Errors), strings.Join(lines, "\n"))
+}
+
+// SyntaxError is a config file that is not YAML or does not fit the
+// config's structure.
+type SyntaxError struct {
+	Err error
+}
+
+func (e *SyntaxError) Error() string {
+	return "parsing config: " + e.Err.Error()
+}
+
+func (e *SyntaxError) Unwrap() error {
+	return e.Err
+}
+
+type validator struct {
+	errs []FieldError
+}
+
+func (v *validator) addf(path, format string, args ...any) {
+	v.errs = append(v.errs, FieldError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) domain(path, d string) {
+	if err := ValidateDomain(d); err != nil {
+		v.addf(path, "%v", err)
+	}
+}
+
+func (v *validator) duration(path string, d Duration) {
+	if d.Duration < 0 {
+		v.addf(path, "must not be negative")
+	}
+}
+
+func (v *validator) positive(path string, d Duration) {
+	if d.Duration <= 0 {
+		v.addf(path, "must be greater than zero")
+	}
+}
+
+func (v *validator) budget(path string, b Budget) {
+	v.duration(path+".daily", b.Daily)
+	v.duration(path+".weekly", b.Weekly)
+}
+
+// Validate checks every field and returns a *ValidationError listing all
+// problems, or nil if the config is usable.
+func (c *Config) Validate() error {
+	v := &validator{}
+
+	seen := make(map[string]int)
+	for i, d := range c.Domains {
+		path := fmt.Sprintf("domains[%d]", i)
+		v.domain(path, d)
+		key := NormalizeDomain(d)
+		if j, ok := seen[key]; ok {
+			v.addf(path, "duplicate of domains[%d]", j)
+			continue
+		}
+		seen[key] = i
+	}
+
+	for _, d := range sortedKeys(c.DomainSettings) {
+		path := "domain_settings." + d
+		v.domain(path, d)
+		for i, sub := range c.DomainSettings[d].Subdomains {
+			name := sub
+			if !strings.Contains(sub, ".") {
+				name = sub + "." + d
+			}
+			if !validHostname(strings.ToLower(name)) {
+				v.addf(fmt.Sprintf("%s.subdomains[%d]", path, i), "%q is not a valid label or host name", sub)
+			}
+		}
+	}
+
+	for _, name := range c.GroupNames() {
+		g := c.Groups[name]
+		path := "groups." + name
+		if err := ValidateGroupName(name); err != nil {
+			v.addf(path, "group names must not be empty or contain %q, commas or spaces", GroupPrefix)
+		}
+		for i, d := range g.Domains {
+			v.domain(fmt.Sprintf("%s.domains[%d]", path, i), d)
+		}
+		v.budget(path+".budget", g.Budget)
+		v.duration(path+".default_duration", g.DefaultDuration)
+		v.duration(path+".max_unblock_duration", g.MaxUnblockDuration)
+	}
+
+	for i, s := range c.Schedules {
+		path := fmt.Sprintf("schedules[%d]", i)
+		if err := s.Validate(); err != nil {
+			v.addf(path, "%v", err)
+		}
+		for j, d := range s.Domains {
+			v.domain(fmt.Sprintf("%s.domains[%d]", path, j), d)
+		}
+	}
+
+	v.budget("budgets.default", c.Budgets.Default)
+	for _, d := range sortedKeys(c.Budgets.Domains) {
+		v.domain("budgets.domains."+d, d)
+		v.budget("budgets.domains."+d, c.Budgets.Domains[d])
+	}
+
+	v.duration("cooldowns.default", c.Cooldowns.Default)
+	for _, d := range sortedKeys(c.Cooldowns.Domains) {
+		v.domain("cooldowns.domains."+d, d)
+		v.duration("cooldowns.domains."+d, c.Cooldowns.Domains[d])
+	}
+
+	c.Access.validate(v)
+	c.Settings.validate(v)
+
+	if len(v.errs) > 0 {
+		return &ValidationError{Errors: v.errs}
+	}
+	return nil
+}
+
+func (s Settings) validate(v *validator) {
+	v.positive("settings.default_duration", s.DefaultDuration)
+	v.positive("settings.check_interval", s.CheckInterval)
+	v.duration("settings.max_unblock_duration", s.MaxUnblockDuration)
+	if max := s.MaxUnblockDuration.Duration; max > 0 && s.DefaultDuration.Duration > max {
+		v.addf("settings.default_duration", "must not exceed max_unblock_duration (%s)", max)
+	}
+
+	flushers := make(map[string]bool)
+	for _, name := range dns.Names() {
+		flushers[name] = true
+	}
+	for i, name := range s.DNSFlushers {
+		if !flushers[name] {
+			v.addf(fmt.Sprintf("settings.dns_flushers[%d]", i), "unknown DNS flusher %q (known: %s)", name, strings.Join(dns.Names(), ", "))
+		}
+	}
+	for i, name := range s.DisabledFlushers {
+		if !flushers[name] {
+			v.addf(fmt.Sprintf("settings.disabled_dns_flushers[%d]", i), "unknown DNS flusher %q (known: %s)", name, strings.Join(dns.Names(), ", "))
+		}
+	}
+
+	if len(s.Backends) == 0 {
+		v.addf("settings.backends", "at least one backend required")
+	}
+	backends := make(map[string]bool)
+	for i, name := range s.Backends {
+		path := fmt.Sprintf("settings.backends[%d]", i)
+		switch name {
+		case BackendHosts, BackendDNS, BackendNFTables:
+		default:
+			v.addf(path, "unknown backend %q (known: %s, %s, %s)", name, BackendHosts, BackendDNS, BackendNFTables)
+		}
+		if backends[name] {
+			v.addf(path, "duplicate backend %q", name)
+		}
+		backends[name] = true
+	}
+
+	if _, _, err := net.SplitHostPort(s.DNSProxy.Listen); err != nil {
+		v.addf("settings.dns_proxy.listen", "must be host:port: %v", err)
+	}
+	for i, u := range s.DNSProxy.Upstreams {
+		if _, err := netip.ParseAddr(u); err == nil {
+			continue
+		}
+		if _, err := netip.ParseAddrPort(u); err != nil {
+			v.addf(fmt.Sprintf("settings.dns_proxy.upstreams[%d]", i), "%q is not an IP address or ip:port", u)
+		}
+	}
+
+	if listen := s.HTTPAPI.Listen; listen != "" {
+		if path, ok := strings.CutPrefix(listen, "unix:"); ok {
+			if !filepath.IsAbs(path) {
+				v.addf("settings.http_api.listen", "unix socket path must be absolute")
+			}
+		} else if host, _, err := net.SplitHostPort(listen); err != nil {
+			v.addf("settings.http_api.listen", "must be host:port or unix:/path: %v", err)
+		} else if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			v.addf("settings.http_api.listen", "must listen on a loopback address such as 127.0.0.1, not %q", host)
+		}
+	}
+	if f := s.HTTPAPI.TokenFile; f != "" && !filepath.IsAbs(f) {
+		v.addf("settings.http_api.token_file", "must be an absolute path")
+	}
+
+	v.duration("settings.nftables.refresh_interval", s.NFTables.RefreshInterval)
+	for _, d := range sortedKeys(s.NFTables.IPRanges) {
+		path := "settings.nftables.ip_ranges." + d
+		v.domain(path, d)
+		for i, r := range s.NFTables.IPRanges[d] {
+			if _, err := netip.ParsePrefix(r); err == nil {
+				continue
+			}
+			if _, err := netip.ParseAddr(r); err != nil {
+				v.addf(fmt.Sprintf("%s[%d]", path, i), "%q is not an IP address or CIDR", r)
+			}
+		}
+	}
+}
+
+// ValidateGroupName checks that name is usable as a group name: not empty
+// and free of the group prefix, commas and whitespace.
+func ValidateGroupName(name string) error {
+	if name == "" || strings.ContainsAny(name, GroupPrefix+", \t\r\n") {
+		return fmt.Errorf("%q is not a valid group name: it must not be empty or contain %q, commas or spaces", name, GroupPrefix)
+	}
+	return nil
+}
+
+// ValidateDomain checks that d is a bare, fully qualified host name such as
+// "reddit.com". URLs and host:port forms are rejected with a suggestion.
+func ValidateDomain(d string) error {
+	lower := strings.ToLower(strings.TrimSpace(d))
+	bare := NormalizeDomain(d)
+	if bare != lower {
+		if validHostname(bare) {
+			return fmt.Errorf("%q is not a bare domain, use %q", d, bare)
+		}
+		return fmt.Errorf("%q is not a valid domain", d)
+	}
+	if !validHostname(bare) {
+		return fmt.Errorf("%q is not a valid domain", d)
+	}
+	if !strings.Contains(bare, ".") {
+		return fmt.Errorf("%q is not a fully qualified domain such as reddit.com", d)
+	}
+	return nil
+}
+
+// NormalizeDomain reduces user input such as "https://Reddit.com:443/r/all"
+// or "reddit.com." to the bare, lower-case host name "reddit.com".
+func NormalizeDomain(d string) string {
+	d = strings.ToLower(strings.TrimSpace(d))
+	if i := strings.Index(d, "://"); i >= 0 {
+		d = d[i+3:]
+	}
+	if i := strings.IndexAny(d, "/?#"); i >= 0 {
+		d = d[:i]
+	}
+	if i := strings.LastIndex(d, "@"); i >= 0 {
+		d = d[i+1:]
+	}
+	if host, _, err := net.SplitHostPort(d); err == nil {
+		d = host
+	}
+	return strings.TrimRight(d, ".")
+}
+
+func validHostname(name string) bool {
+	if name == "" || len(name) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(name, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, r := range label {
+			if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' && r != '_' {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,105 @@
+package daemon
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"sc/internal/config"
+	"sc/internal/ipc"
+	"sc/internal/logs"
+)
+
+// actions maps each command to the access rule that governs it.
+var actions = map[string]string{
+	ipc.CmdStatus:      config.ActionRead,
+	ipc.CmdList:        config.ActionRead,
+	ipc.CmdGet:         config.ActionRead,
+	ipc.CmdSubscribe:   config.ActionRead,
+	ipc.CmdAdd:         config.ActionBlock,
+	ipc.CmdReblock:     config.ActionBlock,
+	ipc.CmdFocus:       config.ActionBlock,
+	ipc.CmdUnblock:     config.ActionUnblock,
+	ipc.CmdRemove:      config.ActionRemove,
+	ipc.CmdSet:         config.ActionConfigure,
+	ipc.CmdApplyConfig: config.ActionConfigure,
+	ipc.CmdReload:      config.ActionConfigure,
+}
+
+// authorize checks command against the access rules for the peer that sent
+// it. A peer that cannot be identified only gets through open rules.
+// Refusals are logged as security events.
+func (s *Server) authorize(command string, p *peer, peerErr error) *ipc.Error {
+	action, ok := actions[command]
+	if !ok {
+		return nil // dispatch rejects unknown commands
+	}
+
+	s.daemon.mu.RLock()
+	rule := s.daemon.cfg.Access.Rule(action)
+	s.daemon.mu.RUnlock()
+
+	if allowed(rule, p) {
+		return nil
+	}
+	return s.deny(command, action, fmt.Sprintf("see access.%s in the config", action), p, peerErr, nil)
+}
+
+// accessCheck refuses a config change that needs more than the configure
+// permission. old is the running config; the caller holds d.mu.
+type accessCheck func(old, next *config.Config) error
+
+// configAccess returns the access check for a config change that p sent
+// with command. Dropping domains needs the remove permission; new or wider
+// schedules and raised budgets or limits need unblock; only root may
+// change access itself.
+func (s *Server) configAccess(command string, p *peer, peerErr error) accessCheck {
+	return func(old, next *config.Config) error {
+		if !old.Access.Equal(next.Access) && (p == nil || p.uid != 0) {
+			return s.deny(command, "change access rules", "only root may", p, peerErr, nil)
+		}
+
+		loosened := config.LooseningsByAction(old, next)
+		for _, action := range []string{config.ActionRemove, config.ActionUnblock} {
+			if len(loosened[action]) > 0 && !allowed(old.Access.Rule(action), p) {
+				return s.deny(command, action, fmt.Sprintf("see access.%s in the config", action), p, peerErr, loosened[action])
+			}
+		}
+		return nil
+	}
+}
+
+// allowed reports whether rule lets p through. A peer that cannot be
+// identified only gets through open rules.
+func allowed(rule config.AccessRule, p *peer) bool {
+	return rule.Open() || (p != nil && rule.Allows(p.uid, p.userName(), p.groups()))
+}
+
+// deny logs command as refused for p, which may not do what, and returns
+// the error for the client. changes are the config changes that needed the
+// permission, if any.
+func (s *Server) deny(command, what, hint string, p *peer, peerErr error, changes []string) *ipc.Error {
+	who := "unidentified caller"
+	if p != nil {
+		who = p.logPeer().String()
+	}
+	reason := fmt.Sprintf("%s refused for %s: not allowed to %s", command, who, what)
+	if p == nil {
+		reason += fmt.Sprintf(" (%v)", peerErr)
+	}
+
+	s.logger.Warn().Str("command", command).Str("action", what).Str("peer", who).Strs("changes", changes).Msg("request denied")
+	logs.Append(config.LogsPath(), logs.Entry{
+		Timestamp: time.Now(),
+		Event:     "denied",
+		Reason:    reason,
+		Changes:   changes,
+		Peer:      p.logPeer(),
+	})
+
+	msg := fmt.Sprintf("permission denied: %s may not %s (%s)", who, what, hint)
+	if len(changes) > 0 {
+		msg += ":\n  " + strings.Join(changes, "\n  ")
+	}
+	return ipc.Errorf(ipc.CodePermissionDenied, "%s", msg)
+}
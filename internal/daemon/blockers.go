@@ -0,0 +1,161 @@
+package daemon
+
+import (
+	"fmt"
+	"net"
+	"runtime"
+	"time"
+
+	"sc/internal/blocker"
+	"sc/internal/config"
+	"sc/internal/dnsproxy"
+	"sc/internal/hosts"
+	"sc/internal/ipc"
+	"sc/internal/logs"
+	"sc/internal/nft"
+)
+
+// SetBlockers overrides the blockers chosen from settings.backends, e.g. to
+// run the daemon against a blocker.Memory instead of the real system.
+func (d *Daemon) SetBlockers(blockers ...blocker.Blocker) {
+	d.blockers = blockers
+	d.fixedBlockers = true
+}
+
+// newBlocker constructs the blocking backend registered under name.
+func (d *Daemon) newBlocker(name string) (blocker.Blocker, error) {
+	switch name {
+	case config.BackendHosts:
+		return hosts.New(), nil
+	case config.BackendDNS:
+		return dnsproxy.New(d.cfg.Settings.DNSProxy.Listen, d.upstreams(), d.logger), nil
+	case config.BackendNFTables:
+		if runtime.GOOS != "linux" {
+			return nil, fmt.Errorf("the nftables backend is only available on Linux")
+		}
+		settings := d.cfg.Settings.NFTables
+		refresh := settings.RefreshInterval.Duration
+		if refresh <= 0 {
+			refresh = 10 * time.Minute
+		}
+		upstreams := d.upstreams()
+		resolve := func(host string) ([]net.IP, error) {
+			return dnsproxy.LookupAny(upstreams, host)
+		}
+
+		// Never cut the daemon off from the servers it resolves through.
+		var exclude []string
+		for _, u := range upstreams {
+			if host, _, err := net.SplitHostPort(u); err == nil {
+				exclude = append(exclude, host)
+			}
+		}
+		return nft.New(nft.ExecRunner{}, resolve, nft.Options{
+			Ranges:  settings.IPRanges,
+			Refresh: refresh,
+			Exclude: exclude,
+		})
+	default:
+		return nil, fmt.Errorf("unknown blocking backend %q", name)
+	}
+}
+
+// upstreams returns the DNS servers used to forward or resolve queries
+// without going through sc's own blocks.
+func (d *Daemon) upstreams() []string {
+	settings := d.cfg.Settings.DNSProxy
+	if len(settings.Upstreams) > 0 {
+		return settings.Upstreams
+	}
+	if system := dnsproxy.SystemUpstreams(settings.Listen); len(system) > 0 {
+		return system
+	}
+	return dnsproxy.DefaultUpstreams
+}
+
+func (d *Daemon) startBlockers() error {
+	if d.blockers == nil {
+		for _, name := range d.cfg.Settings.Backends {
+			b, err := d.newBlocker(name)
+			if err != nil {
+				return err
+			}
+			d.blockers = append(d.blockers, b)
+		}
+
+		// Clean up after the hosts backend if it was switched off.
+		if !d.cfg.Settings.UsesBackend(config.BackendHosts) {
+			if err := hosts.Remove(); err != nil {
+				d.logger.Warn().Err(err).Msg("failed to clean hosts file")
+			}
+		}
+	}
+
+	for i, b := range d.blockers {
+		if svc, ok := b.(blocker.Service); ok {
+			if err := svc.Start(); err != nil {
+				d.blockers = d.blockers[:i]
+				d.stopBlockers()
+				return fmt.Errorf("start %s blocker: %w", b.Name(), err)
+			}
+		}
+	}
+
+	names := make([]string, len(d.blockers))
+	for i, b := range d.blockers {
+		names[i] = b.Name()
+	}
+	d.logger.Info().Strs("blockers", names).Msg("blocking backends ready")
+	return nil
+}
+
+func (d *Daemon) stopBlockers() {
+	for _, b := range d.blockers {
+		if svc, ok := b.(blocker.Service); ok {
+			svc.Stop()
+		}
+	}
+}
+
+// entries builds the full set of blocks to enforce right now, including the
+// DoH protection set when enabled. Protection entries are never unblocked.
+func (d *Daemon) entries(now time.Time) []blocker.Entry {
+	unblocked := d.unblockedSet(now)
+	opts := d.expandOptions()
+
+	var entries []blocker.Entry
+	for _, domain := range d.blockList() {
+		if !unblocked[domain] {
+			entries = append(entries, blocker.Entry{Domain: domain, Hosts: blocker.Expand(domain, opts)})
+		}
+	}
+	if d.cfg.Settings.BlockDoH {
+		entries = append(entries, blocker.DoHEntries()...)
+	}
+	return entries
+}
+
+// verify checks every blocker is enforcing the current block list and logs
+// any that are not.
+func (d *Daemon) verify(now time.Time) {
+	entries := d.entries(now)
+	for _, b := range d.blockers {
+		if err := b.Verify(entries); err != nil {
+			d.logger.Warn().Err(err).Str("blocker", b.Name()).Msg("block verification failed")
+		}
+	}
+}
+
+// reportTampers logs outside changes a blocker found and reverted.
+func (d *Daemon) reportTampers(name string, tampers []blocker.Tamper) {
+	for _, t := range tampers {
+		d.logger.Warn().Str("blocker", name).Str("detail", t.Detail).Str("diff", t.Diff).Msg("tamper detected, restoring blocks")
+		logs.Append(config.LogsPath(), logs.Entry{
+			Timestamp: time.Now(),
+			Event:     "tamper",
+			Reason:    name + ": " + t.Detail,
+			Diff:      t.Diff,
+		})
+		d.publish(ipc.Event{Type: ipc.EventTamper, Reason: name + ": " + t.Detail})
+	}
+}
@@ -0,0 +1,197 @@
+package daemon
+
+import (
+	"fmt"
+	"time"
+
+	"sc/internal/config"
+	"sc/internal/logs"
+	"sc/internal/schedule"
+)
+
+const (
+	dayFormat   = "2006-01-02"
+	historyDays = 7
+)
+
+// BudgetError is returned by Unblock when a domain has no budget left.
+type BudgetError struct {
+	Domain string
+	Period string
+	Budget time.Duration
+}
+
+func (e *BudgetError) Error() string {
+	return fmt.Sprintf("%s budget of %s for %s is used up", e.Period, e.Budget, e.Domain)
+}
+
+// recordUsage books the unblocked interval [start, end) against domain,
+// split across calendar days, and logs each day's share.
+func (d *Daemon) recordUsage(domain string, start, end time.Time) {
+	if d.state.Usage == nil {
+		d.state.Usage = make(map[string]map[string]config.Duration)
+	}
+
+	for start.Before(end) {
+		chunkEnd := schedule.Midnight(start).AddDate(0, 0, 1)
+		if end.Before(chunkEnd) {
+			chunkEnd = end
+		}
+
+		day := start.Format(dayFormat)
+		if d.state.Usage[day] == nil {
+			d.state.Usage[day] = make(map[string]config.Duration)
+		}
+		used := chunkEnd.Sub(start)
+		total := d.state.Usage[day][domain]
+		total.Duration += used
+		d.state.Usage[day][domain] = total
+
+		logs.Append(config.LogsPath(), logs.Entry{
+			Timestamp: start,
+			Event:     "usage",
+			Domain:    domain,
+			Duration:  used.Round(time.Second).String(),
+		})
+
+		start = chunkEnd
+	}
+
+	d.pruneUsage(end)
+}
+
+func (d *Daemon) pruneUsage(now time.Time) {
+	cutoff := schedule.Midnight(now).AddDate(0, 0, -historyDays).Format(dayFormat)
+	for day := range d.state.Usage {
+		if day < cutoff {
+			delete(d.state.Usage, day)
+		}
+	}
+}
+
+// usedSince returns the unblocked time the domains have consumed since the
+// given instant, including any unblocks still in progress.
+func (d *Daemon) usedSince(domains []string, since, now time.Time) time.Duration {
+	var used time.Duration
+	for _, domain := range domains {
+		for t := schedule.Midnight(since); !t.After(now); t = t.AddDate(0, 0, 1) {
+			used += d.state.Usage[t.Format(dayFormat)][domain].Duration
+		}
+
+		if ub, ok := d.state.Unblocked[domain]; ok {
+			start, end := ub.Started, ub.Until
+			if start.Before(since) {
+				start = since
+			}
+			if now.Before(end) {
+				end = now
+			}
+			if end.After(start) {
+				used += end.Sub(start)
+			}
+		}
+	}
+	return used
+}
+
+type budgetScope struct {
+	name    string
+	domains []string
+	budget  config.Budget
+}
+
+// budgetScopes lists every budget that applies to domain: its own (or the
+// default) plus the shared budget of each group it belongs to.
+func (d *Daemon) budgetScopes(domain string) []budgetScope {
+	var scopes []budgetScope
+	if b := d.cfg.BudgetFor(domain); !b.IsZero() {
+		scopes = append(scopes, budgetScope{name: domain, domains: []string{domain}, budget: b})
+	}
+	for _, name := range d.cfg.GroupsOf(domain) {
+		g := d.cfg.Groups[name]
+		if !g.Budget.IsZero() {
+			scopes = append(scopes, budgetScope{name: config.GroupPrefix + name, domains: g.Domains, budget: g.Budget})
+		}
+	}
+	return scopes
+}
+
+// scopeRemaining returns the time left in a budget scope for each of
+// members domains unblocked together, or a BudgetError when less than a
+// second of either its daily or weekly limit is left for each.
+func (d *Daemon) scopeRemaining(sc budgetScope, members int, now time.Time) (time.Duration, error) {
+	today := schedule.Midnight(now)
+	periods := []struct {
+		name   string
+		budget time.Duration
+		since  time.Time
+	}{
+		{"daily", sc.budget.Daily.Duration, today},
+		{"weekly", sc.budget.Weekly.Duration, today.AddDate(0, 0, -(historyDays - 1))},
+	}
+
+	remaining := time.Duration(-1)
+	for _, p := range periods {
+		if p.budget <= 0 {
+			continue
+		}
+		left := (p.budget - d.usedSince(sc.domains, p.since, now)) / time.Duration(members)
+		if left < time.Second {
+			return 0, &BudgetError{Domain: sc.name, Period: p.name, Budget: p.budget}
+		}
+		if remaining < 0 || left < remaining {
+			remaining = left
+		}
+	}
+	return remaining, nil
+}
+
+// budgetRemaining returns how much more unblocked time domain may use now.
+// The second result is false when no budget applies to the domain.
+func (d *Daemon) budgetRemaining(domain string, now time.Time) (time.Duration, bool, error) {
+	scopes := d.budgetScopes(domain)
+	if len(scopes) == 0 {
+		return 0, false, nil
+	}
+
+	remaining := time.Duration(-1)
+	for _, sc := range scopes {
+		left, err := d.scopeRemaining(sc, 1, now)
+		if err != nil {
+			return 0, true, err
+		}
+		if remaining < 0 || left < remaining {
+			remaining = left
+		}
+	}
+	return remaining, true, nil
+}
+
+// unblockCap returns the longest duration the domains may all be unblocked
+// for together. A shared group budget is split between the requested members
+// so that unblocking a whole group cannot overspend it.
+func (d *Daemon) unblockCap(domains []string, now time.Time) (time.Duration, bool, error) {
+	scopes := make(map[string]budgetScope)
+	members := make(map[string]int)
+	for _, domain := range domains {
+		for _, sc := range d.budgetScopes(domain) {
+			scopes[sc.name] = sc
+			members[sc.name]++
+		}
+	}
+	if len(scopes) == 0 {
+		return 0, false, nil
+	}
+
+	limit := time.Duration(-1)
+	for name, sc := range scopes {
+		share, err := d.scopeRemaining(sc, members[name], now)
+		if err != nil {
+			return 0, true, err
+		}
+		if limit < 0 || share < limit {
+			limit = share
+		}
+	}
+	return limit, true, nil
+}
@@ -0,0 +1,116 @@
+package daemon
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"sc/internal/config"
+	"sc/internal/ipc"
+	"sc/internal/logs"
+)
+
+// LooseningError is returned by ApplyConfig when a new config would weaken
+// blocking while a focus session or a friction rule forbids it.
+type LooseningError struct {
+	Reason  string
+	Changes []string
+}
+
+func (e *LooseningError) Error() string {
+	return fmt.Sprintf("%s — refusing to loosen the config:\n  %s", e.Reason, strings.Join(e.Changes, "\n  "))
+}
+
+// ApplyConfig validates raw config file contents, checks they do not loosen
+// anything that is currently locked, switches to them and writes them to the
+// config file on behalf of by, once check, if set, allows it. The file is
+// written verbatim so comments survive.
+func (d *Daemon) ApplyConfig(raw []byte, by *logs.Peer, check accessCheck) (ipc.ReloadData, error) {
+	next, err := config.Parse(raw)
+	if err != nil {
+		return ipc.ReloadData{}, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if check != nil {
+		if err := check(d.cfg, next); err != nil {
+			return ipc.ReloadData{}, err
+		}
+	}
+	now := time.Now()
+	if err := d.checkLoosening(next, now); err != nil {
+		d.logger.Warn().Err(err).Msg("config edit refused")
+		return ipc.ReloadData{}, err
+	}
+
+	prev := d.cfg
+	data, err := d.swapConfig(next, "edit", by)
+	if err != nil {
+		return ipc.ReloadData{}, err
+	}
+	if err := config.WriteFile(d.cfgPath, raw); err != nil {
+		d.swapConfig(prev, "rollback", by)
+		return ipc.ReloadData{}, fmt.Errorf("writing config: %w", err)
+	}
+	return data, nil
+}
+
+// checkLoosening refuses next if it weakens blocking during a focus session,
+// or if it would free a domain that is cooling down or out of budget. The
+// caller holds d.mu.
+func (d *Daemon) checkLoosening(next *config.Config, now time.Time) *LooseningError {
+	loosened := config.Loosenings(d.cfg, next)
+	if len(loosened) == 0 {
+		return nil
+	}
+
+	if d.focusActive(now) {
+		until := d.state.Focus.Until
+		return &LooseningError{
+			Reason:  fmt.Sprintf("focus session active until %s (%s left)", until.Format("15:04"), until.Sub(now).Round(time.Second)),
+			Changes: loosened,
+		}
+	}
+
+	freeNow := d.unblockedSet(now)
+	var freeNext map[string]bool
+	d.withConfig(next, func() { freeNext = d.unblockedSet(now) })
+
+	for _, domain := range d.cfg.Domains {
+		var reason string
+		if err := d.checkCooldown(domain, now); err != nil {
+			reason = err.Error()
+		} else if _, ok, err := d.budgetRemaining(domain, now); ok && err != nil {
+			var stillOut bool
+			d.withConfig(next, func() {
+				_, ok, err := d.budgetRemaining(domain, now)
+				stillOut = ok && err != nil
+			})
+			if !stillOut {
+				return &LooseningError{Reason: err.Error(), Changes: []string{"budget for " + domain + " raised or removed"}}
+			}
+			reason = err.Error()
+		}
+		if reason == "" {
+			continue
+		}
+
+		switch {
+		case !next.HasDomain(domain):
+			return &LooseningError{Reason: reason, Changes: []string{"domains: " + domain + " removed"}}
+		case !freeNow[domain] && freeNext[domain]:
+			return &LooseningError{Reason: reason, Changes: []string{"schedules: " + domain + " would be allowed now"}}
+		}
+	}
+	return nil
+}
+
+// withConfig runs fn with cfg temporarily in force. The caller holds d.mu.
+func (d *Daemon) withConfig(cfg *config.Config, fn func()) {
+	prev := d.cfg
+	d.cfg = cfg
+	defer func() { d.cfg = prev }()
+	fn()
+}
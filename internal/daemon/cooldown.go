@@ -0,0 +1,51 @@
+package daemon
+
+import (
+	"fmt"
+	"time"
+)
+
+// CooldownError is returned by Unblock when a domain was reblocked too
+// recently to be unblocked again.
+type CooldownError struct {
+	Domain string
+	Until  time.Time
+}
+
+func (e *CooldownError) Error() string {
+	wait := time.Until(e.Until).Round(time.Second)
+	return fmt.Sprintf("%s is cooling down — eligible for unblock at %s (in %s)",
+		e.Domain, e.Until.Format("15:04:05"), wait)
+}
+
+// startCooldown records when domain becomes eligible for unblocking again
+// after being reblocked at the given time.
+func (d *Daemon) startCooldown(domain string, reblocked time.Time) {
+	cd := d.cfg.CooldownFor(domain)
+	if cd <= 0 {
+		return
+	}
+	if d.state.Cooldowns == nil {
+		d.state.Cooldowns = make(map[string]time.Time)
+	}
+	d.state.Cooldowns[domain] = reblocked.Add(cd)
+}
+
+func (d *Daemon) checkCooldown(domain string, now time.Time) error {
+	if until, ok := d.state.Cooldowns[domain]; ok && now.Before(until) {
+		return &CooldownError{Domain: domain, Until: until}
+	}
+	return nil
+}
+
+// expireCooldowns drops finished cooldowns and reports whether any were removed.
+func (d *Daemon) expireCooldowns(now time.Time) bool {
+	changed := false
+	for domain, until := range d.state.Cooldowns {
+		if !now.Before(until) {
+			delete(d.state.Cooldowns, domain)
+			changed = true
+		}
+	}
+	return changed
+}
@@ -2,14 +2,17 @@ package daemon
 
 import (
 	"context"
+	"fmt"
+	"slices"
 	"sync"
 	"time"
 
+	"sc/internal/blocker"
 	"sc/internal/config"
 	"sc/internal/dns"
-	"sc/internal/hosts"
 	"sc/internal/ipc"
 	"sc/internal/logs"
+	"sc/internal/schedule"
 
 	"github.com/rs/zerolog"
 	"gopkg.in/yaml.v3"
@@ -23,7 +26,10 @@ type UnblockEntry struct {
 }
 
 type State struct {
-	Unblocked map[string]UnblockEntry `yaml:"unblocked"`
+	Unblocked map[string]UnblockEntry               `yaml:"unblocked"`
+	Usage     map[string]map[string]config.Duration `yaml:"usage,omitempty"`
+	Cooldowns map[string]time.Time                  `yaml:"cooldowns,omitempty"`
+	Focus     *FocusSession                         `yaml:"focus,omitempty"`
 }
 
 type Daemon struct {
@@ -31,8 +37,31 @@ type Daemon struct {
 	cfgPath   string
 	state     *State
 	logger    zerolog.Logger
+	flushers  []dns.Flusher
+	blockers  []blocker.Blocker
+	allowed   map[string]bool
 	mu        sync.RWMutex
 	startTime time.Time
+
+	// watching is set when file notifications trigger enforcement, so the
+	// tick only needs to re-apply as a safety net.
+	watching     bool
+	watched      []string
+	lastEnforced time.Time
+
+	// timers holds pending reblocks; rearm wakes Run when one is added.
+	timers timerHeap
+	rearm  chan struct{}
+
+	// reloaded wakes Run after a config reload so it can pick up a new
+	// check interval and watch list.
+	reloaded      chan struct{}
+	fixedBlockers bool
+
+	// subscribers receive events; guarded by subMu so publishing never
+	// depends on d.mu.
+	subMu       sync.Mutex
+	subscribers map[chan ipc.Event]bool
 }
 
 func New(cfg *config.Config, cfgPath string, logger zerolog.Logger) *Daemon {
@@ -40,6 +69,9 @@ func New(cfg *config.Config, cfgPath string, logger zerolog.Logger) *Daemon {
 		cfg:       cfg,
 		cfgPath:   cfgPath,
 		state:     &State{Unblocked: make(map[string]UnblockEntry)},
+		allowed:   make(map[string]bool),
+		rearm:     make(chan struct{}, 1),
+		reloaded:  make(chan struct{}, 1),
 		logger:    logger,
 		startTime: time.Now(),
 	}
@@ -47,12 +79,34 @@ func New(cfg *config.Config, cfgPath string, logger zerolog.Logger) *Daemon {
 
 func (d *Daemon) Run(ctx context.Context) error {
 	d.loadState()
+	d.holdFocusConfig()
+	d.detectFlushers()
+
+	if err := d.startBlockers(); err != nil {
+		return err
+	}
+	defer d.stopBlockers()
+
 	d.tick()
 
+	w := d.startWatcher()
+	defer func() {
+		if w != nil {
+			w.Close()
+		}
+	}()
+
 	interval := d.cfg.Settings.CheckInterval.Duration
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
+	pending := make(map[string]bool)
+	var settle <-chan time.Time
+
+	expiryTimer := time.NewTimer(0)
+	defer expiryTimer.Stop()
+	d.armExpiry(expiryTimer)
+
 	d.logger.Info().
 		Dur("check_interval", interval).
 		Dur("default_duration", d.cfg.Settings.DefaultDuration.Duration).
@@ -66,46 +120,59 @@ func (d *Daemon) Run(ctx context.Context) error {
 			return nil
 		case <-ticker.C:
 			d.tick()
+		case <-expiryTimer.C:
+			d.expireUnblocks(time.Now())
+			d.armExpiry(expiryTimer)
+		case <-d.rearm:
+			d.armExpiry(expiryTimer)
+		case <-d.reloaded:
+			d.mu.RLock()
+			interval := d.cfg.Settings.CheckInterval.Duration
+			d.mu.RUnlock()
+			if interval > 0 {
+				ticker.Reset(interval)
+			}
+			w = d.restartWatcher(w)
+		case path := <-w.Events():
+			pending[path] = true
+			if settle == nil {
+				settle = time.After(settleDelay)
+			}
+		case <-settle:
+			settle = nil
+			d.filesChanged(pending)
+			pending = make(map[string]bool)
 		}
 	}
 }
 
 func (d *Daemon) tick() {
+	// Once a focus session ends, re-read the config file to pick up edits
+	// it held back. This runs after d.mu is released.
+	focusEnded := false
+	defer func() {
+		if focusEnded {
+			d.Reload("focus ended", nil, nil)
+		}
+	}()
+
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
 	changed := false
 	now := time.Now()
 
-	for domain, entry := range d.state.Unblocked {
-		if now.After(entry.Until) {
-			delete(d.state.Unblocked, domain)
-			changed = true
-			d.logger.Info().Str("domain", domain).Msg("timer expired, reblocking")
-			logs.Append(config.LogsPath(), logs.Entry{
-				Timestamp: now,
-				Event:     "reblock",
-				Domain:    domain,
-				Reason:    "timer_expired",
-			})
-		}
-	}
-
-	unblocked := make(map[string]bool)
-	for domain := range d.state.Unblocked {
-		unblocked[domain] = true
+	if d.expireCooldowns(now) {
+		changed = true
 	}
-
-	hostsChanged, err := hosts.Apply(d.cfg.Domains, unblocked, d.cfg.Settings.BlockSubdomains)
-	if err != nil {
-		d.logger.Error().Err(err).Msg("failed to apply hosts")
-		return
+	if d.expireFocus(now) {
+		changed = true
+		focusEnded = true
 	}
+	scheduled := d.trackSchedules(now)
 
-	if hostsChanged && d.cfg.Settings.FlushDNS {
-		if err := dns.Flush(); err != nil {
-			d.logger.Warn().Err(err).Msg("failed to flush DNS")
-		}
+	if changed || scheduled || !d.watching || now.Sub(d.lastEnforced) >= safetyInterval {
+		d.enforce(now)
 	}
 
 	if changed {
@@ -113,45 +180,118 @@ func (d *Daemon) tick() {
 	}
 }
 
-func (d *Daemon) Unblock(domains []string, duration time.Duration) ipc.UnblockData {
+// Unblock unblocks domains, or every configured domain if none are given,
+// for duration capped by the max unblock duration and remaining budgets.
+func (d *Daemon) Unblock(domains []string, duration time.Duration, by *logs.Peer) (ipc.UnblockData, error) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
 	now := time.Now()
+	if err := d.checkFocus(now); err != nil {
+		return ipc.UnblockData{}, err
+	}
+	limited := false
+
+	if len(domains) > 0 {
+		for _, domain := range domains {
+			if !d.cfg.HasDomain(domain) {
+				return ipc.UnblockData{}, ipc.Errorf(ipc.CodeNotFound, "domain %q not in block list", domain)
+			}
+		}
+	} else {
+		domains = slices.Clone(d.cfg.Domains)
+	}
+
+	if max := d.cfg.MaxUnblockFor(domains); max > 0 && duration > max {
+		duration = max
+	}
+
+	for _, domain := range domains {
+		if err := d.checkCooldown(domain, now); err != nil {
+			return ipc.UnblockData{}, err
+		}
+	}
+
+	remaining, ok, err := d.unblockCap(domains, now)
+	if err != nil {
+		return ipc.UnblockData{}, err
+	}
+	if ok && remaining < duration {
+		duration = remaining.Truncate(time.Second)
+		limited = true
+	}
+
 	until := now.Add(duration)
 
 	for _, domain := range domains {
+		if prev, ok := d.state.Unblocked[domain]; ok {
+			d.recordUsage(domain, prev.Started, now)
+		}
 		d.state.Unblocked[domain] = UnblockEntry{Until: until, Started: now}
+		d.scheduleExpiry(domain, until)
 		logs.Append(config.LogsPath(), logs.Entry{
 			Timestamp: now,
 			Event:     "unblock",
 			Domain:    domain,
 			Duration:  duration.String(),
+			Peer:      by,
 		})
 		d.logger.Info().Str("domain", domain).Dur("duration", duration).Msg("unblocked")
 	}
+	d.publish(ipc.Event{
+		Type:            ipc.EventUnblocked,
+		Domains:         domains,
+		Duration:        duration.String(),
+		DurationSeconds: seconds(duration),
+	})
 
 	d.applyAndFlush()
 	d.saveState()
 
-	return ipc.UnblockData{Domains: domains, Duration: duration.String()}
+	return ipc.UnblockData{
+		Domains:         domains,
+		Duration:        duration.String(),
+		DurationSeconds: seconds(duration),
+		BudgetLimited:   limited,
+	}, nil
+}
+
+// seconds returns d in whole seconds, rounded, for the *_seconds fields of
+// IPC responses.
+func seconds(d time.Duration) int64 {
+	return int64(d.Round(time.Second) / time.Second)
 }
 
-func (d *Daemon) Reblock(domains []string) ipc.ReblockData {
+func (d *Daemon) Reblock(domains []string, by *logs.Peer) ipc.ReblockData {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
-	now := time.Now()
+	reblocked := d.reblock(domains, time.Now(), "manual", by)
+
+	d.applyAndFlush()
+	d.saveState()
+
+	return ipc.ReblockData{Domains: reblocked}
+}
+
+// reblock ends the unblocks for domains (all if empty) and logs them with
+// the given reason and requester. The caller holds d.mu and applies the
+// result.
+func (d *Daemon) reblock(domains []string, now time.Time, reason string, by *logs.Peer) []string {
 	var reblocked []string
 
 	if len(domains) == 0 {
-		for domain := range d.state.Unblocked {
+		for domain, entry := range d.state.Unblocked {
+			d.recordUsage(domain, entry.Started, now)
+			d.startCooldown(domain, now)
 			reblocked = append(reblocked, domain)
 		}
 		d.state.Unblocked = make(map[string]UnblockEntry)
 	} else {
 		for _, domain := range domains {
-			if _, ok := d.state.Unblocked[domain]; ok {
+			if entry, ok := d.state.Unblocked[domain]; ok {
+				d.recordUsage(domain, entry.Started, now)
+				d.startCooldown(domain, now)
 				delete(d.state.Unblocked, domain)
 				reblocked = append(reblocked, domain)
 			}
@@ -163,45 +303,89 @@ func (d *Daemon) Reblock(domains []string) ipc.ReblockData {
 			Timestamp: now,
 			Event:     "reblock",
 			Domain:    domain,
-			Reason:    "manual",
+			Reason:    reason,
+			Peer:      by,
 		})
-		d.logger.Info().Str("domain", domain).Msg("manually reblocked")
+		d.logger.Info().Str("domain", domain).Str("reason", reason).Msg("reblocked")
+	}
+	if len(reblocked) > 0 {
+		d.publish(ipc.Event{Type: ipc.EventReblocked, Domains: reblocked, Reason: reason})
 	}
 
-	d.applyAndFlush()
-	d.saveState()
-
-	return ipc.ReblockData{Domains: reblocked}
+	return reblocked
 }
 
-func (d *Daemon) AddDomains(domains []string) ipc.MutateData {
+// AddDomains adds domains to the block list and, if group is non-empty, to
+// that group as well.
+func (d *Daemon) AddDomains(domains []string, group string, by *logs.Peer) (ipc.MutateData, error) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
+	next, err := d.cfg.Clone()
+	if err != nil {
+		return ipc.MutateData{}, err
+	}
 	var added []string
 	for _, domain := range domains {
-		if d.cfg.AddDomain(domain) {
+		addedDomain := next.AddDomain(domain)
+		addedGroup := group != "" && next.AddToGroup(group, domain)
+		if addedDomain || addedGroup {
 			added = append(added, domain)
 		}
 	}
+	if len(added) == 0 {
+		return ipc.MutateData{Domains: slices.Clone(d.cfg.Domains)}, nil
+	}
 
-	if len(added) > 0 {
-		config.Save(d.cfg, d.cfgPath)
-		d.applyAndFlush()
+	if err := next.Validate(); err != nil {
+		return ipc.MutateData{}, err
+	}
+	if err := config.Save(next, d.cfgPath); err != nil {
+		return ipc.MutateData{}, fmt.Errorf("writing config: %w", err)
 	}
+	d.cfg = next
 
-	return ipc.MutateData{Added: added, Domains: d.cfg.Domains}
+	now := time.Now()
+	for _, domain := range added {
+		logs.Append(config.LogsPath(), logs.Entry{
+			Timestamp: now,
+			Event:     "add",
+			Domain:    domain,
+			Reason:    group,
+			Peer:      by,
+		})
+	}
+	d.applyAndFlush()
+	d.pinConfig()
+	d.saveState()
+	d.publish(ipc.Event{Type: ipc.EventDomainAdded, Domains: added, Reason: group})
+
+	return ipc.MutateData{Added: added, Domains: slices.Clone(d.cfg.Domains)}, nil
 }
 
-func (d *Daemon) RemoveDomains(domains []string) ipc.MutateData {
+func (d *Daemon) RemoveDomains(domains []string, by *logs.Peer) (ipc.MutateData, error) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
+	now := time.Now()
+	if err := d.checkFocus(now); err != nil {
+		return ipc.MutateData{}, err
+	}
+
 	var removed []string
 	for _, domain := range domains {
 		if d.cfg.RemoveDomain(domain) {
 			removed = append(removed, domain)
-			delete(d.state.Unblocked, domain)
+			logs.Append(config.LogsPath(), logs.Entry{
+				Timestamp: now,
+				Event:     "remove",
+				Domain:    domain,
+				Peer:      by,
+			})
+			if entry, ok := d.state.Unblocked[domain]; ok {
+				d.recordUsage(domain, entry.Started, now)
+				delete(d.state.Unblocked, domain)
+			}
 		}
 	}
 
@@ -209,9 +393,10 @@ func (d *Daemon) RemoveDomains(domains []string) ipc.MutateData {
 		config.Save(d.cfg, d.cfgPath)
 		d.applyAndFlush()
 		d.saveState()
+		d.publish(ipc.Event{Type: ipc.EventDomainRemoved, Domains: removed})
 	}
 
-	return ipc.MutateData{Removed: removed, Domains: d.cfg.Domains}
+	return ipc.MutateData{Removed: removed, Domains: slices.Clone(d.cfg.Domains)}, nil
 }
 
 func (d *Daemon) Status() ipc.StatusData {
@@ -219,51 +404,163 @@ func (d *Daemon) Status() ipc.StatusData {
 	defer d.mu.RUnlock()
 
 	now := time.Now()
+	focused := d.focusActive(now)
 	var entries []ipc.StatusEntry
 
-	for _, domain := range d.cfg.Domains {
-		entry := ipc.StatusEntry{Domain: domain}
+	for _, domain := range d.blockList() {
+		entry := ipc.StatusEntry{Domain: domain, State: "blocked", Groups: d.cfg.GroupsOf(domain)}
+		if st, ok := schedule.Evaluate(d.cfg.Schedules, domain, now); ok {
+			entry.Schedule = st.Schedule
+			entry.ScheduleState = "blocked"
+			if st.Allowed {
+				entry.ScheduleState = "allowed"
+				if !focused {
+					entry.State = "allowed"
+				}
+			}
+			if !st.Next.IsZero() {
+				entry.NextTransition = st.Next.Format(time.RFC3339)
+			}
+		}
 		if ub, ok := d.state.Unblocked[domain]; ok {
-			remaining := ub.Until.Sub(now)
-			if remaining > 0 {
+			if remaining := ub.Until.Sub(now); remaining > 0 {
 				entry.State = "unblocked"
 				entry.Remaining = remaining.Round(time.Second).String()
-			} else {
-				entry.State = "blocked"
+				entry.RemainingSeconds = seconds(remaining)
 			}
-		} else {
-			entry.State = "blocked"
+		}
+		if until, ok := d.state.Cooldowns[domain]; ok && now.Before(until) {
+			entry.Cooldown = until.Sub(now).Round(time.Second).String()
+			entry.CooldownSeconds = seconds(until.Sub(now))
+		}
+		if left, ok, err := d.budgetRemaining(domain, now); ok {
+			if err != nil {
+				left = 0
+			}
+			entry.BudgetRemaining = left.Round(time.Second).String()
+			secs := seconds(left)
+			entry.BudgetRemainingSeconds = &secs
 		}
 		entries = append(entries, entry)
 	}
 
-	return ipc.StatusData{
-		Uptime:  time.Since(d.startTime).Round(time.Second).String(),
-		Domains: entries,
+	uptime := time.Since(d.startTime)
+	data := ipc.StatusData{
+		Uptime:        uptime.Round(time.Second).String(),
+		UptimeSeconds: seconds(uptime),
+		Domains:       entries,
+	}
+	if focused {
+		data.FocusUntil = d.state.Focus.Until.Format(time.RFC3339)
+		data.FocusRemaining = d.state.Focus.Until.Sub(now).Round(time.Second).String()
+		data.FocusRemainingSeconds = seconds(d.state.Focus.Until.Sub(now))
 	}
+	return data
 }
 
-func (d *Daemon) ListDomains() ipc.ListData {
+// ListDomains returns the block list and groups. With expanded set it also
+// returns the host names each domain is written as.
+func (d *Daemon) ListDomains(expanded bool) ipc.ListData {
 	d.mu.RLock()
 	defer d.mu.RUnlock()
-	return ipc.ListData{Domains: d.cfg.Domains}
+
+	groups := make(map[string][]string, len(d.cfg.Groups))
+	for name, g := range d.cfg.Groups {
+		groups[name] = slices.Clone(g.Domains)
+	}
+	data := ipc.ListData{Domains: slices.Clone(d.cfg.Domains), Groups: groups}
+
+	if expanded {
+		opts := d.expandOptions()
+		data.Expanded = make(map[string][]string, len(d.cfg.Domains))
+		for _, domain := range d.cfg.Domains {
+			data.Expanded[domain] = blocker.Expand(domain, opts)
+		}
+	}
+	return data
 }
 
 func (d *Daemon) applyAndFlush() {
+	entries := d.entries(time.Now())
+	changed := false
+
+	for _, b := range d.blockers {
+		bChanged, err := b.Apply(entries)
+		if td, ok := b.(blocker.TamperDetector); ok {
+			d.reportTampers(b.Name(), td.Tampers())
+		}
+		if err != nil {
+			d.logger.Error().Err(err).Str("blocker", b.Name()).Msg("failed to apply blocks")
+			continue
+		}
+		changed = changed || bChanged
+	}
+
+	if changed {
+		d.flushDNS()
+	}
+}
+
+// unblockedSet returns the domains that should currently be left out of the
+// block list, either because of a timed unblock or an open schedule window.
+func (d *Daemon) unblockedSet(now time.Time) map[string]bool {
 	unblocked := make(map[string]bool)
+	if d.focusActive(now) {
+		return unblocked
+	}
 	for domain := range d.state.Unblocked {
 		unblocked[domain] = true
 	}
+	for _, domain := range d.cfg.Domains {
+		if st, ok := schedule.Evaluate(d.cfg.Schedules, domain, now); ok && st.Allowed {
+			unblocked[domain] = true
+		}
+	}
+	return unblocked
+}
 
-	changed, err := hosts.Apply(d.cfg.Domains, unblocked, d.cfg.Settings.BlockSubdomains)
+// trackSchedules logs schedule windows opening and closing and reports
+// whether any did since the last call.
+func (d *Daemon) trackSchedules(now time.Time) bool {
+	changed := false
+	for _, domain := range d.cfg.Domains {
+		st, _ := schedule.Evaluate(d.cfg.Schedules, domain, now)
+		if st.Allowed == d.allowed[domain] {
+			continue
+		}
+		changed = true
+		if st.Allowed {
+			d.allowed[domain] = true
+			d.logger.Info().Str("domain", domain).Str("schedule", st.Schedule).Msg("schedule window opened")
+		} else {
+			delete(d.allowed, domain)
+			d.logger.Info().Str("domain", domain).Str("schedule", st.Schedule).Msg("schedule window closed")
+		}
+	}
+	return changed
+}
+
+func (d *Daemon) detectFlushers() {
+	flushers, err := dns.Detect(d.cfg.Settings.DNSFlushers, d.cfg.Settings.DisabledFlushers)
 	if err != nil {
-		d.logger.Error().Err(err).Msg("failed to apply hosts")
+		d.logger.Error().Err(err).Msg("invalid DNS flusher config, DNS flushing disabled")
 		return
 	}
-	if changed && d.cfg.Settings.FlushDNS {
-		if err := dns.Flush(); err != nil {
-			d.logger.Warn().Err(err).Msg("failed to flush DNS")
-		}
+
+	names := make([]string, len(flushers))
+	for i, f := range flushers {
+		names[i] = f.Name()
+	}
+	d.flushers = flushers
+	d.logger.Info().Strs("dns_flushers", names).Msg("detected DNS flushers")
+}
+
+func (d *Daemon) flushDNS() {
+	if !d.cfg.Settings.FlushDNS {
+		return
+	}
+	if err := dns.Flush(d.flushers); err != nil {
+		d.logger.Warn().Err(err).Msg("failed to flush DNS")
 	}
 }
 
@@ -283,16 +580,20 @@ func (d *Daemon) loadState() {
 		state.Unblocked = make(map[string]UnblockEntry)
 	}
 
+	d.state = &state
+
 	// Expire past-due timers
 	now := time.Now()
 	for domain, entry := range state.Unblocked {
 		if now.After(entry.Until) {
 			delete(state.Unblocked, domain)
+			d.recordUsage(domain, entry.Started, entry.Until)
+			d.startCooldown(domain, entry.Until)
 			d.logger.Info().Str("domain", domain).Msg("expired stale unblock on startup")
+			continue
 		}
+		d.scheduleExpiry(domain, entry.Until)
 	}
-
-	d.state = &state
 }
 
 func (d *Daemon) saveState() {
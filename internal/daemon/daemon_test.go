@@ -0,0 +1,541 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"sc/internal/blocker"
+	"sc/internal/config"
+	"sc/internal/ipc"
+	"sc/internal/logs"
+
+	"github.com/rs/zerolog"
+)
+
+const twoDomains = `
+domains: [example.com, news.com]
+settings:
+  flush_dns: false
+`
+
+// newTestDaemon returns a daemon for the config file contents cfgYAML that
+// blocks through a blocker.Memory and keeps its config, state and logs in
+// a temporary directory.
+func newTestDaemon(t *testing.T, cfgYAML string) (*Daemon, *blocker.Memory) {
+	t.Helper()
+	return newDaemonIn(t, t.TempDir(), cfgYAML)
+}
+
+// newDaemonIn is newTestDaemon with the data directory given, so a test
+// can restart a daemon on the state of a previous one.
+func newDaemonIn(t *testing.T, dir, cfgYAML string) (*Daemon, *blocker.Memory) {
+	t.Helper()
+
+	prev := config.DataDir()
+	config.SetDataDir(dir)
+	t.Cleanup(func() { config.SetDataDir(prev) })
+
+	cfgPath := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(cfgPath, []byte(cfgYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Parse([]byte(cfgYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := New(cfg, cfgPath, zerolog.Nop())
+	mem := blocker.NewMemory()
+	d.SetBlockers(mem)
+	d.loadState()
+	d.holdFocusConfig()
+	d.applyAndFlush()
+	return d, mem
+}
+
+// runDaemon runs d's main loop until the test ends.
+func runDaemon(t *testing.T, d *Daemon) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := d.Run(ctx); err != nil {
+			t.Error(err)
+		}
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+// blocked returns the domains mem is currently blocking.
+func blocked(mem *blocker.Memory) map[string]bool {
+	result := make(map[string]bool)
+	for _, e := range mem.Entries() {
+		result[e.Domain] = true
+	}
+	return result
+}
+
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting until %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestUnblockExpires(t *testing.T) {
+	d, mem := newTestDaemon(t, twoDomains)
+	runDaemon(t, d)
+
+	events, cancel := d.Subscribe()
+	defer cancel()
+
+	data, err := d.Unblock([]string{"example.com"}, 200*time.Millisecond, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(data.Domains, []string{"example.com"}) || data.BudgetLimited {
+		t.Errorf("Unblock = %+v", data)
+	}
+	if got := blocked(mem); got["example.com"] || !got["news.com"] {
+		t.Errorf("blocked after unblock = %v, want only news.com", got)
+	}
+
+	eventually(t, "example.com is reblocked", func() bool { return blocked(mem)["example.com"] })
+
+	var reblocked *ipc.Event
+	for reblocked == nil {
+		select {
+		case ev := <-events:
+			if ev.Type == ipc.EventReblocked {
+				reblocked = &ev
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("no reblocked event")
+		}
+	}
+	if reblocked.Reason != "timer_expired" || !slices.Equal(reblocked.Domains, []string{"example.com"}) {
+		t.Errorf("reblocked event = %+v", *reblocked)
+	}
+
+	d.mu.RLock()
+	_, stillUnblocked := d.state.Unblocked["example.com"]
+	d.mu.RUnlock()
+	if stillUnblocked {
+		t.Error("example.com still recorded as unblocked")
+	}
+
+	entries, err := logs.Query(config.LogsPath(), logs.QueryOpts{Domain: "example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.Event+":"+e.Reason)
+	}
+	if want := []string{"unblock:", "usage:", "reblock:timer_expired"}; !slices.Equal(got, want) {
+		t.Errorf("log entries = %v, want %v", got, want)
+	}
+}
+
+func TestUnblockChecks(t *testing.T) {
+	d, _ := newTestDaemon(t, `
+domains: [example.com, news.com]
+settings:
+  flush_dns: false
+  max_unblock_duration: 30m
+`)
+
+	data, err := d.Unblock(nil, time.Hour, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.Duration != "30m0s" {
+		t.Errorf("duration = %s, want capped to 30m0s", data.Duration)
+	}
+	if !slices.Equal(data.Domains, []string{"example.com", "news.com"}) {
+		t.Errorf("domains = %v, want every configured domain", data.Domains)
+	}
+
+	_, err = d.Unblock([]string{"other.com"}, time.Minute, nil)
+	if e := errorFor(err); e.Code != ipc.CodeNotFound {
+		t.Errorf("unblocking an unlisted domain = %v, want %s", err, ipc.CodeNotFound)
+	}
+}
+
+func TestFocusRefusesLoosening(t *testing.T) {
+	d, mem := newTestDaemon(t, twoDomains)
+
+	if _, err := d.Unblock([]string{"example.com"}, time.Hour, nil); err != nil {
+		t.Fatal(err)
+	}
+	focus := d.Focus(time.Hour, nil)
+	if !slices.Equal(focus.Reblocked, []string{"example.com"}) {
+		t.Errorf("focus reblocked %v, want [example.com]", focus.Reblocked)
+	}
+	if !blocked(mem)["example.com"] {
+		t.Error("example.com not blocked after focus started")
+	}
+
+	var focusErr *FocusError
+	if _, err := d.Unblock([]string{"news.com"}, time.Minute, nil); !errors.As(err, &focusErr) {
+		t.Errorf("Unblock during focus = %v, want FocusError", err)
+	}
+	if _, err := d.RemoveDomains([]string{"news.com"}, nil); !errors.As(err, &focusErr) {
+		t.Errorf("RemoveDomains during focus = %v, want FocusError", err)
+	}
+
+	looser := "domains: [example.com]\nsettings:\n  flush_dns: false\n"
+	var looseningErr *LooseningError
+	if _, err := d.ApplyConfig([]byte(looser), nil, nil); !errors.As(err, &looseningErr) {
+		t.Errorf("ApplyConfig during focus = %v, want LooseningError", err)
+	}
+	if _, err := d.SetSetting("settings.block_subdomains", "false", nil, nil); !errors.As(err, &looseningErr) {
+		t.Errorf("SetSetting during focus = %v, want LooseningError", err)
+	}
+
+	// Editing the file directly is no way around it.
+	if err := os.WriteFile(d.cfgPath, []byte(looser), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Reload("file", nil, nil); !errors.As(err, &looseningErr) {
+		t.Errorf("Reload during focus = %v, want LooseningError", err)
+	}
+	if !d.cfg.HasDomain("news.com") || !blocked(mem)["news.com"] {
+		t.Error("news.com dropped from the block list during focus")
+	}
+
+	// Tightening is still allowed.
+	if data, err := d.AddDomains([]string{"extra.com"}, "", nil); err != nil || !slices.Equal(data.Added, []string{"extra.com"}) {
+		t.Errorf("AddDomains during focus = %v, %v", data.Added, err)
+	}
+	if !blocked(mem)["extra.com"] {
+		t.Error("extra.com not blocked after adding it during focus")
+	}
+	if pinned := d.state.Focus.Config; pinned == d.cfg || !pinned.HasDomain("extra.com") {
+		t.Error("focus session does not hold its own copy of the tightened config")
+	}
+}
+
+func TestFocusSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	d, _ := newDaemonIn(t, dir, `
+domains: [example.com, news.com]
+settings:
+  flush_dns: false
+  block_doh: true
+`)
+	d.Focus(time.Hour, nil)
+
+	// The config file is loosened while the daemon is stopped.
+	restarted, mem := newDaemonIn(t, dir, `
+domains: [example.com]
+settings:
+  flush_dns: false
+`)
+	if !restarted.focusActive(time.Now()) {
+		t.Fatal("focus session lost on restart")
+	}
+	if !restarted.cfg.Settings.BlockDoH || !restarted.cfg.HasDomain("news.com") {
+		t.Error("restart with a looser config file weakened the focus session")
+	}
+	got := blocked(mem)
+	if !got["news.com"] || !got[blocker.FirefoxCanary] {
+		t.Errorf("blocked after restart = %v, want news.com and the DoH set", got)
+	}
+}
+
+func TestCooldown(t *testing.T) {
+	d, mem := newTestDaemon(t, `
+domains: [example.com, news.com]
+cooldowns:
+  default: 30m
+settings:
+  flush_dns: false
+`)
+
+	if _, err := d.Unblock([]string{"example.com"}, 10*time.Minute, nil); err != nil {
+		t.Fatal(err)
+	}
+	d.Reblock([]string{"example.com"}, nil)
+	if !blocked(mem)["example.com"] {
+		t.Fatal("example.com not blocked after reblock")
+	}
+
+	var cooldownErr *CooldownError
+	for _, domains := range [][]string{{"example.com"}, nil} {
+		if _, err := d.Unblock(domains, time.Minute, nil); !errors.As(err, &cooldownErr) {
+			t.Errorf("Unblock(%v) while cooling down = %v, want CooldownError", domains, err)
+		}
+	}
+	if _, err := d.Unblock([]string{"news.com"}, time.Minute, nil); err != nil {
+		t.Errorf("Unblock of a domain without a cooldown = %v", err)
+	}
+
+	var looseningErr *LooseningError
+	looser := "domains: [news.com]\ncooldowns:\n  default: 30m\nsettings:\n  flush_dns: false\n"
+	if _, err := d.ApplyConfig([]byte(looser), nil, nil); !errors.As(err, &looseningErr) {
+		t.Errorf("removing a cooling domain = %v, want LooseningError", err)
+	}
+
+	d.mu.Lock()
+	d.expireCooldowns(time.Now().Add(31 * time.Minute))
+	d.mu.Unlock()
+	if _, err := d.Unblock([]string{"example.com"}, time.Minute, nil); err != nil {
+		t.Errorf("Unblock after the cooldown = %v", err)
+	}
+}
+
+func TestBudgetLimitsUnblock(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      string
+		domains     []string
+		request     time.Duration
+		want        string
+		wantLimited bool
+	}{
+		{
+			name:        "truncated to budget",
+			config:      "domains: [example.com]\nbudgets:\n  default:\n    daily: 10m\n",
+			domains:     []string{"example.com"},
+			request:     time.Hour,
+			want:        "10m0s",
+			wantLimited: true,
+		},
+		{
+			name:    "within budget",
+			config:  "domains: [example.com]\nbudgets:\n  default:\n    daily: 10m\n",
+			domains: []string{"example.com"},
+			request: 5 * time.Minute,
+			want:    "5m0s",
+		},
+		{
+			name:        "tightest of daily and weekly",
+			config:      "domains: [example.com]\nbudgets:\n  domains:\n    example.com:\n      daily: 1h\n      weekly: 20m\n",
+			domains:     []string{"example.com"},
+			request:     time.Hour,
+			want:        "20m0s",
+			wantLimited: true,
+		},
+		{
+			name:        "group budget shared",
+			config:      "groups:\n  social:\n    domains: [a.com, b.com]\n    budget:\n      daily: 10m\n",
+			domains:     []string{"a.com", "b.com"},
+			request:     time.Hour,
+			want:        "5m0s",
+			wantLimited: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, _ := newTestDaemon(t, tt.config+"settings:\n  flush_dns: false\n")
+
+			data, err := d.Unblock(tt.domains, tt.request, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if data.Duration != tt.want || data.BudgetLimited != tt.wantLimited {
+				t.Errorf("Unblock = %s (limited %v), want %s (limited %v)",
+					data.Duration, data.BudgetLimited, tt.want, tt.wantLimited)
+			}
+		})
+	}
+}
+
+func TestBudgetExhausted(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		domains []string
+		used    time.Duration
+	}{
+		{
+			name:    "used up",
+			config:  "domains: [example.com]\nbudgets:\n  default:\n    daily: 10m\n",
+			domains: []string{"example.com"},
+			used:    10 * time.Minute,
+		},
+		{
+			name:    "under a second left",
+			config:  "domains: [example.com]\nbudgets:\n  default:\n    daily: 10m\n",
+			domains: []string{"example.com"},
+			used:    10*time.Minute - 400*time.Millisecond,
+		},
+		{
+			name:    "under a second left per group member",
+			config:  "groups:\n  social:\n    domains: [example.com, news.com]\n    budget:\n      daily: 10m\n",
+			domains: []string{"example.com", "news.com"},
+			used:    10*time.Minute - 1500*time.Millisecond,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, _ := newTestDaemon(t, tt.config+"settings:\n  flush_dns: false\n")
+
+			d.mu.Lock()
+			d.state.Usage = map[string]map[string]config.Duration{
+				time.Now().Format(dayFormat): {"example.com": {Duration: tt.used}},
+			}
+			d.mu.Unlock()
+
+			data, err := d.Unblock(tt.domains, time.Minute, nil)
+			var budgetErr *BudgetError
+			if !errors.As(err, &budgetErr) {
+				t.Fatalf("Unblock = %+v, %v, want BudgetError", data, err)
+			}
+			if e := errorFor(err); e.Code != ipc.CodeBudgetExhausted {
+				t.Errorf("error code = %s, want %s", e.Code, ipc.CodeBudgetExhausted)
+			}
+		})
+	}
+}
+
+func TestAddGroupName(t *testing.T) {
+	d, _ := newTestDaemon(t, twoDomains)
+	s := NewServer(d, "", "test", zerolog.Nop())
+
+	data, err := s.handleAdd(ipc.AddRequest{Domains: []string{"x.com"}, Group: "@social"}, nil)
+	if err != nil || !slices.Equal(data.Added, []string{"x.com"}) {
+		t.Fatalf("add to @social = %v, %v", data.Added, err)
+	}
+	if !d.cfg.Groups["social"].Has("x.com") {
+		t.Errorf("groups = %v, want x.com in social", d.cfg.GroupNames())
+	}
+
+	var ipcErr *ipc.Error
+	for _, group := range []string{"@", "a,b", "my group"} {
+		_, err := s.handleAdd(ipc.AddRequest{Domains: []string{"y.com"}, Group: group}, nil)
+		if !errors.As(err, &ipcErr) || ipcErr.Code != ipc.CodeInvalidValue {
+			t.Errorf("add to %q = %v, want %s", group, err, ipc.CodeInvalidValue)
+		}
+	}
+
+	// The daemon refuses a group name that would leave an invalid config.
+	var validationErr *config.ValidationError
+	if _, err := d.AddDomains([]string{"y.com"}, "@bad", nil); !errors.As(err, &validationErr) {
+		t.Errorf("AddDomains to @bad = %v, want ValidationError", err)
+	}
+	if d.cfg.HasDomain("y.com") {
+		t.Error("rejected add changed the running config")
+	}
+	reloaded, err := config.Read(d.cfgPath)
+	if err != nil {
+		t.Fatalf("config file unreadable after adds: %v", err)
+	}
+	if err := reloaded.Validate(); err != nil || reloaded.HasDomain("y.com") {
+		t.Errorf("config file after rejected add: %v, has y.com %v", err, reloaded.HasDomain("y.com"))
+	}
+}
+
+const restrictedAccess = `
+domains: [example.com, news.com]
+access:
+  remove:
+    users: ["1001"]
+  unblock:
+    users: ["1002"]
+settings:
+  flush_dns: false
+  max_unblock_duration: 1h
+`
+
+func TestConfigAccess(t *testing.T) {
+	const (
+		dropped = "domains: [example.com]\n"
+		window  = "schedules:\n  - name: evenings\n    domains: [news.com]\n    allow:\n      - from: \"18:00\"\n        to: \"22:00\"\n"
+		opened  = "  configure:\n    users: [\"1000\"]\n"
+	)
+	edited := func(old, new string) string { return strings.Replace(restrictedAccess, old, new, 1) }
+
+	apply := func(raw string) func(*Daemon, accessCheck) error {
+		return func(d *Daemon, check accessCheck) error {
+			_, err := d.ApplyConfig([]byte(raw), nil, check)
+			return err
+		}
+	}
+	set := func(key, value string) func(*Daemon, accessCheck) error {
+		return func(d *Daemon, check accessCheck) error {
+			_, err := d.SetSetting(key, value, nil, check)
+			return err
+		}
+	}
+	reload := func(raw string) func(*Daemon, accessCheck) error {
+		return func(d *Daemon, check accessCheck) error {
+			if err := os.WriteFile(d.cfgPath, []byte(raw), 0644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := d.Reload("ipc", nil, check)
+			return err
+		}
+	}
+
+	tests := []struct {
+		name   string
+		caller *peer
+		change func(*Daemon, accessCheck) error
+		denied bool
+	}{
+		{"drop a domain", &peer{uid: 1000}, apply(edited("domains: [example.com, news.com]\n", dropped)), true},
+		{"drop a domain with remove", &peer{uid: 1001}, apply(edited("domains: [example.com, news.com]\n", dropped)), false},
+		{"drop a domain unidentified", nil, apply(edited("domains: [example.com, news.com]\n", dropped)), true},
+		{"add a domain", &peer{uid: 1000}, apply(edited("news.com]", "news.com, extra.com]")), false},
+		{"add an allow window", &peer{uid: 1000}, apply(restrictedAccess + window), true},
+		{"add an allow window with unblock", &peer{uid: 1002}, apply(restrictedAccess + window), false},
+		{"raise a limit", &peer{uid: 1001}, set("settings.max_unblock_duration", "2h"), true},
+		{"lower a limit", &peer{uid: 1000}, set("settings.max_unblock_duration", "30m"), false},
+		{"change access", &peer{uid: 1002}, reload(edited("access:\n", "access:\n"+opened)), true},
+		{"change access as root", &peer{uid: 0}, reload(edited("access:\n", "access:\n"+opened)), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, _ := newTestDaemon(t, restrictedAccess)
+			s := NewServer(d, "", "test", zerolog.Nop())
+			before := d.cfg
+
+			err := tt.change(d, s.configAccess("test", tt.caller, errors.New("no credentials")))
+			if !tt.denied {
+				if err != nil {
+					t.Fatalf("change refused: %v", err)
+				}
+				if d.cfg == before {
+					t.Error("config not switched")
+				}
+				return
+			}
+
+			var ipcErr *ipc.Error
+			if !errors.As(err, &ipcErr) || ipcErr.Code != ipc.CodePermissionDenied {
+				t.Fatalf("change = %v, want %s", err, ipc.CodePermissionDenied)
+			}
+			if d.cfg != before {
+				t.Error("refused change switched the config")
+			}
+			entries, err := logs.Query(config.LogsPath(), logs.QueryOpts{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 || entries[0].Event != "denied" {
+				t.Errorf("log entries = %+v, want one denied entry", entries)
+			}
+		})
+	}
+}
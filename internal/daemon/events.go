@@ -0,0 +1,53 @@
+package daemon
+
+import (
+	"time"
+
+	"sc/internal/ipc"
+)
+
+// subscriberBuffer is how many events a subscriber may fall behind before
+// it is dropped; it can reconnect and re-read status.
+const subscriberBuffer = 64
+
+// Subscribe registers for events. The channel is closed when cancel is
+// called or when the subscriber falls too far behind.
+func (d *Daemon) Subscribe() (events <-chan ipc.Event, cancel func()) {
+	ch := make(chan ipc.Event, subscriberBuffer)
+
+	d.subMu.Lock()
+	if d.subscribers == nil {
+		d.subscribers = make(map[chan ipc.Event]bool)
+	}
+	d.subscribers[ch] = true
+	d.subMu.Unlock()
+
+	return ch, func() {
+		d.subMu.Lock()
+		defer d.subMu.Unlock()
+		if d.subscribers[ch] {
+			delete(d.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// publish sends ev to every subscriber without blocking. It is safe to call
+// with d.mu held.
+func (d *Daemon) publish(ev ipc.Event) {
+	if ev.Time == "" {
+		ev.Time = time.Now().Format(time.RFC3339)
+	}
+
+	d.subMu.Lock()
+	defer d.subMu.Unlock()
+	for ch := range d.subscribers {
+		select {
+		case ch <- ev:
+		default:
+			d.logger.Warn().Msg("event subscriber too slow, disconnecting")
+			delete(d.subscribers, ch)
+			close(ch)
+		}
+	}
+}
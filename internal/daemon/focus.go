@@ -0,0 +1,182 @@
+package daemon
+
+import (
+	"fmt"
+	"time"
+
+	"sc/internal/blocker"
+	"sc/internal/config"
+	"sc/internal/ipc"
+	"sc/internal/logs"
+)
+
+// FocusSession is a lockdown during which nothing may be unblocked or
+// loosened. The domains and settings in force when it started are kept so
+// that editing the config file and restarting cannot shrink the block list.
+// Config is a copy of the running config, refreshed as it is tightened.
+type FocusSession struct {
+	Started         time.Time      `yaml:"started"`
+	Until           time.Time      `yaml:"until"`
+	Domains         []string       `yaml:"domains"`
+	BlockSubdomains bool           `yaml:"block_subdomains"`
+	Config          *config.Config `yaml:"config,omitempty"`
+}
+
+// FocusError is returned for any request refused because of a focus session.
+type FocusError struct {
+	Until time.Time
+}
+
+func (e *FocusError) Error() string {
+	return fmt.Sprintf("focus session active until %s (%s left) — request refused",
+		e.Until.Format("15:04"), time.Until(e.Until).Round(time.Second))
+}
+
+// Focus reblocks everything and starts (or extends) a focus session. A
+// running session can only be lengthened, never shortened.
+func (d *Daemon) Focus(duration time.Duration, by *logs.Peer) ipc.FocusData {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	until := now.Add(duration)
+
+	if d.focusActive(now) {
+		if until.After(d.state.Focus.Until) {
+			d.state.Focus.Until = until
+		}
+		d.state.Focus.Domains = mergeDomains(d.state.Focus.Domains, d.cfg.Domains)
+		d.state.Focus.BlockSubdomains = d.state.Focus.BlockSubdomains || d.cfg.Settings.BlockSubdomains
+	} else {
+		d.state.Focus = &FocusSession{
+			Started:         now,
+			Until:           until,
+			Domains:         append([]string(nil), d.cfg.Domains...),
+			BlockSubdomains: d.cfg.Settings.BlockSubdomains,
+		}
+	}
+	d.pinConfig()
+
+	reblocked := d.reblock(nil, now, "focus", by)
+
+	logs.Append(config.LogsPath(), logs.Entry{
+		Timestamp: now,
+		Event:     "focus",
+		Duration:  d.state.Focus.Until.Sub(now).Round(time.Second).String(),
+		Peer:      by,
+	})
+	d.logger.Info().Time("until", d.state.Focus.Until).Msg("focus session started")
+	left := d.state.Focus.Until.Sub(now)
+	d.publish(ipc.Event{
+		Type:            ipc.EventFocusStarted,
+		Duration:        left.Round(time.Second).String(),
+		DurationSeconds: seconds(left),
+	})
+
+	d.applyAndFlush()
+	d.saveState()
+
+	return ipc.FocusData{
+		Until:            d.state.Focus.Until.Format(time.RFC3339),
+		Remaining:        d.state.Focus.Until.Sub(now).Round(time.Second).String(),
+		RemainingSeconds: seconds(d.state.Focus.Until.Sub(now)),
+		Reblocked:        reblocked,
+	}
+}
+
+func (d *Daemon) focusActive(now time.Time) bool {
+	return d.state.Focus != nil && now.Before(d.state.Focus.Until)
+}
+
+func (d *Daemon) checkFocus(now time.Time) error {
+	if d.focusActive(now) {
+		return &FocusError{Until: d.state.Focus.Until}
+	}
+	return nil
+}
+
+// pinConfig copies the running config into the focus session, if one is
+// active. The caller holds d.mu and saves the state.
+func (d *Daemon) pinConfig() {
+	if !d.focusActive(time.Now()) {
+		return
+	}
+	pinned, err := d.cfg.Clone()
+	if err != nil {
+		d.logger.Error().Err(err).Msg("failed to copy config into focus session")
+		return
+	}
+	d.state.Focus.Config = pinned
+}
+
+// holdFocusConfig switches back to the config pinned by a running focus
+// session if the config file loaded at startup would loosen it, e.g.
+// because it was edited while the daemon was stopped. The file is read
+// again once the session ends.
+func (d *Daemon) holdFocusConfig() {
+	if !d.focusActive(time.Now()) {
+		return
+	}
+
+	// Sessions saved by earlier releases have no Config; they pin only
+	// Domains and BlockSubdomains, which blockList and expandOptions apply.
+	pinned := d.state.Focus.Config
+	if pinned == nil {
+		d.pinConfig()
+		return
+	}
+
+	if err := pinned.Validate(); err != nil {
+		d.logger.Warn().Err(err).Msg("ignoring invalid config saved with focus session")
+	} else if loosened := config.Loosenings(pinned, d.cfg); len(loosened) > 0 {
+		d.logger.Warn().Time("until", d.state.Focus.Until).Strs("changes", loosened).
+			Msg("config file loosens the focus session, keeping the session's config")
+		d.cfg = pinned
+	}
+	d.pinConfig()
+}
+
+// expireFocus clears a finished focus session and reports whether it did.
+func (d *Daemon) expireFocus(now time.Time) bool {
+	if d.state.Focus == nil || d.focusActive(now) {
+		return false
+	}
+	d.logger.Info().Msg("focus session ended")
+	d.state.Focus = nil
+	return true
+}
+
+// blockList returns the configured domains plus any domains pinned by an
+// active focus session.
+func (d *Daemon) blockList() []string {
+	if !d.focusActive(time.Now()) {
+		return d.cfg.Domains
+	}
+	return mergeDomains(d.cfg.Domains, d.state.Focus.Domains)
+}
+
+func (d *Daemon) expandOptions() blocker.ExpandOptions {
+	opts := blocker.ExpandOptions{
+		BlockSubdomains: d.cfg.Settings.BlockSubdomains,
+		Subdomains:      d.cfg.Subdomains(),
+	}
+	if d.focusActive(time.Now()) && d.state.Focus.BlockSubdomains {
+		opts.BlockSubdomains = true
+	}
+	return opts
+}
+
+func mergeDomains(a, b []string) []string {
+	seen := make(map[string]bool, len(a))
+	result := append([]string(nil), a...)
+	for _, domain := range a {
+		seen[domain] = true
+	}
+	for _, domain := range b {
+		if !seen[domain] {
+			seen[domain] = true
+			result = append(result, domain)
+		}
+	}
+	return result
+}
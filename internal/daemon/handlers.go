@@ -0,0 +1,226 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"sc/internal/config"
+	"sc/internal/ipc"
+	"sc/internal/logs"
+)
+
+// dispatch decodes payload into the command's request struct and runs it on
+// behalf of caller, which is recorded in log entries. Config changes are checked
+// against the access rules they get around; see configAccess. Failures are
+// returned as *ipc.Error; see errorFor.
+func (s *Server) dispatch(command string, payload json.RawMessage, caller *peer, peerErr error) (any, *ipc.Error) {
+	by := caller.logPeer()
+	var data any
+	var err error
+	switch command {
+	case ipc.CmdStatus:
+		data = s.daemon.Status()
+	case ipc.CmdUnblock:
+		var p ipc.UnblockRequest
+		if err = decode(payload, &p); err == nil {
+			data, err = s.handleUnblock(p, by)
+		}
+	case ipc.CmdReblock:
+		var p ipc.ReblockRequest
+		if err = decode(payload, &p); err == nil {
+			data, err = s.handleReblock(p, by)
+		}
+	case ipc.CmdAdd:
+		var p ipc.AddRequest
+		if err = decode(payload, &p); err == nil {
+			data, err = s.handleAdd(p, by)
+		}
+	case ipc.CmdRemove:
+		var p ipc.RemoveRequest
+		if err = decode(payload, &p); err == nil {
+			data, err = s.handleRemove(p, by)
+		}
+	case ipc.CmdList:
+		var p ipc.ListRequest
+		if err = decode(payload, &p); err == nil {
+			data = s.daemon.ListDomains(p.Expanded)
+		}
+	case ipc.CmdFocus:
+		var p ipc.FocusRequest
+		if err = decode(payload, &p); err == nil {
+			data, err = s.handleFocus(p, by)
+		}
+	case ipc.CmdReload:
+		data, err = s.daemon.Reload("ipc", by, s.configAccess(command, caller, peerErr))
+	case ipc.CmdApplyConfig:
+		var p ipc.ApplyConfigRequest
+		if err = decode(payload, &p); err == nil {
+			data, err = s.handleApplyConfig(p, by, s.configAccess(command, caller, peerErr))
+		}
+	case ipc.CmdGet:
+		var p ipc.GetRequest
+		if err = decode(payload, &p); err == nil {
+			data, err = s.handleGet(p)
+		}
+	case ipc.CmdSet:
+		var p ipc.SetRequest
+		if err = decode(payload, &p); err == nil {
+			data, err = s.handleSet(p, by, s.configAccess(command, caller, peerErr))
+		}
+	default:
+		return nil, ipc.Errorf(ipc.CodeUnknownCommand, "unknown command: %s", command)
+	}
+
+	if err != nil {
+		return nil, errorFor(err)
+	}
+	return data, nil
+}
+
+func decode(payload json.RawMessage, v any) error {
+	if len(payload) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(payload, v); err != nil {
+		return ipc.Errorf(ipc.CodeInvalidRequest, "invalid payload: %v", err)
+	}
+	return nil
+}
+
+// errorFor maps an error from the daemon to its protocol error code.
+// Anything unrecognised is reported as internal.
+func errorFor(err error) *ipc.Error {
+	var ipcErr *ipc.Error
+	var focusErr *FocusError
+	var looseningErr *LooseningError
+	var cooldownErr *CooldownError
+	var budgetErr *BudgetError
+	var validationErr *config.ValidationError
+	var syntaxErr *config.SyntaxError
+	var notFoundErr *config.NotFoundError
+	var valueErr *config.ValueError
+
+	code := ipc.CodeInternal
+	switch {
+	case errors.As(err, &ipcErr):
+		return ipcErr
+	case errors.As(err, &focusErr), errors.As(err, &looseningErr):
+		code = ipc.CodeLocked
+	case errors.As(err, &cooldownErr):
+		code = ipc.CodeCooldown
+	case errors.As(err, &budgetErr):
+		code = ipc.CodeBudgetExhausted
+	case errors.As(err, &validationErr), errors.As(err, &syntaxErr):
+		code = ipc.CodeInvalidConfig
+	case errors.As(err, &notFoundErr):
+		code = ipc.CodeNotFound
+	case errors.As(err, &valueErr):
+		code = ipc.CodeInvalidValue
+	}
+	return &ipc.Error{Code: code, Message: err.Error()}
+}
+
+func (s *Server) handleUnblock(p ipc.UnblockRequest, by *logs.Peer) (ipc.UnblockData, error) {
+	if p.Duration == "" {
+		return ipc.UnblockData{}, ipc.Errorf(ipc.CodeInvalidDuration, "duration required")
+	}
+	dur, err := time.ParseDuration(p.Duration)
+	if err != nil || dur <= 0 {
+		return ipc.UnblockData{}, ipc.Errorf(ipc.CodeInvalidDuration, "invalid duration: %s", p.Duration)
+	}
+
+	domains, err := s.targets(p.Domains)
+	if err != nil {
+		return ipc.UnblockData{}, err
+	}
+	return s.daemon.Unblock(domains, dur, by)
+}
+
+func (s *Server) handleReblock(p ipc.ReblockRequest, by *logs.Peer) (ipc.ReblockData, error) {
+	domains, err := s.targets(p.Domains)
+	if err != nil {
+		return ipc.ReblockData{}, err
+	}
+	return s.daemon.Reblock(domains, by), nil
+}
+
+func (s *Server) handleAdd(p ipc.AddRequest, by *logs.Peer) (ipc.MutateData, error) {
+	if len(p.Domains) == 0 {
+		return ipc.MutateData{}, ipc.Errorf(ipc.CodeInvalidRequest, "domains required")
+	}
+
+	domains := make([]string, len(p.Domains))
+	for i, d := range p.Domains {
+		domains[i] = config.NormalizeDomain(d)
+		if err := config.ValidateDomain(domains[i]); err != nil {
+			return ipc.MutateData{}, ipc.Errorf(ipc.CodeInvalidDomain, "%s", err)
+		}
+	}
+
+	group := p.Group
+	if group != "" {
+		group = strings.TrimPrefix(strings.TrimSpace(group), config.GroupPrefix)
+		if err := config.ValidateGroupName(group); err != nil {
+			return ipc.MutateData{}, ipc.Errorf(ipc.CodeInvalidValue, "%s", err)
+		}
+	}
+
+	return s.daemon.AddDomains(domains, group, by)
+}
+
+func (s *Server) handleRemove(p ipc.RemoveRequest, by *logs.Peer) (ipc.MutateData, error) {
+	domains, err := s.targets(p.Domains)
+	if err != nil {
+		return ipc.MutateData{}, err
+	}
+	if len(domains) == 0 {
+		return ipc.MutateData{}, ipc.Errorf(ipc.CodeInvalidRequest, "domains required")
+	}
+	return s.daemon.RemoveDomains(domains, by)
+}
+
+func (s *Server) handleFocus(p ipc.FocusRequest, by *logs.Peer) (ipc.FocusData, error) {
+	if p.Duration == "" {
+		return ipc.FocusData{}, ipc.Errorf(ipc.CodeInvalidDuration, "duration required")
+	}
+	dur, err := time.ParseDuration(p.Duration)
+	if err != nil || dur <= 0 {
+		return ipc.FocusData{}, ipc.Errorf(ipc.CodeInvalidDuration, "invalid duration: %s", p.Duration)
+	}
+	return s.daemon.Focus(dur, by), nil
+}
+
+func (s *Server) handleApplyConfig(p ipc.ApplyConfigRequest, by *logs.Peer, check accessCheck) (ipc.ReloadData, error) {
+	if p.Config == "" {
+		return ipc.ReloadData{}, ipc.Errorf(ipc.CodeInvalidRequest, "config required")
+	}
+	return s.daemon.ApplyConfig([]byte(p.Config), by, check)
+}
+
+func (s *Server) handleGet(p ipc.GetRequest) (ipc.SettingData, error) {
+	if p.Key == "" {
+		return ipc.SettingData{}, ipc.Errorf(ipc.CodeInvalidRequest, "key required")
+	}
+	return s.daemon.GetSetting(p.Key)
+}
+
+func (s *Server) handleSet(p ipc.SetRequest, by *logs.Peer, check accessCheck) (ipc.SettingData, error) {
+	if p.Key == "" {
+		return ipc.SettingData{}, ipc.Errorf(ipc.CodeInvalidRequest, "key required")
+	}
+	return s.daemon.SetSetting(p.Key, p.Value, by, check)
+}
+
+// targets expands any @group references among domains into their member
+// domains.
+func (s *Server) targets(domains []string) ([]string, error) {
+	if len(domains) == 0 {
+		return nil, nil
+	}
+
+	s.daemon.mu.RLock()
+	defer s.daemon.mu.RUnlock()
+	return s.daemon.cfg.ExpandTargets(domains)
+}
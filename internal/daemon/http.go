@@ -0,0 +1,239 @@
+package daemon
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"sc/internal/config"
+	"sc/internal/ipc"
+
+	"github.com/rs/zerolog"
+)
+
+//go:embed openapi.yaml
+var openAPISpec []byte
+
+// HTTPServer serves a JSON API over HTTP on a loopback address or a unix
+// socket. Each endpoint maps onto a protocol command and goes through the
+// same authorization and validation as the unix socket server, so the two
+// cannot drift apart. The API is described in openapi.yaml, which is served
+// at /v1/openapi.yaml.
+type HTTPServer struct {
+	ipc      *Server
+	listen   string
+	token    string
+	logger   zerolog.Logger
+	srv      *http.Server
+	listener net.Listener
+}
+
+type peerKey struct{}
+
+// peerInfo is the caller identity attached to each HTTP connection.
+type peerInfo struct {
+	peer *peer
+	err  error
+}
+
+// NewHTTPServer returns an HTTP server for the socket server s, configured
+// by api. The token is read from api's token file, which is created with a
+// random token if it does not exist.
+func NewHTTPServer(s *Server, api config.HTTPAPI, logger zerolog.Logger) (*HTTPServer, error) {
+	token, err := loadToken(api.TokenPath())
+	if err != nil {
+		return nil, err
+	}
+
+	h := &HTTPServer{
+		ipc:    s,
+		listen: api.Listen,
+		token:  token,
+		logger: logger,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/openapi.yaml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Write(openAPISpec)
+	})
+	mux.Handle("GET /v1/status", h.endpoint(ipc.CmdStatus, noPayload))
+	mux.Handle("GET /v1/domains", h.endpoint(ipc.CmdList, func(r *http.Request) ([]byte, error) {
+		return json.Marshal(ipc.ListRequest{Expanded: r.URL.Query().Get("expanded") == "true"})
+	}))
+	mux.Handle("POST /v1/domains", h.endpoint(ipc.CmdAdd, bodyPayload))
+	mux.Handle("DELETE /v1/domains/{domain}", h.endpoint(ipc.CmdRemove, func(r *http.Request) ([]byte, error) {
+		return json.Marshal(ipc.RemoveRequest{Domains: []string{r.PathValue("domain")}})
+	}))
+	mux.Handle("POST /v1/unblock", h.endpoint(ipc.CmdUnblock, bodyPayload))
+	mux.Handle("POST /v1/reblock", h.endpoint(ipc.CmdReblock, bodyPayload))
+
+	h.srv = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+		ConnContext: func(ctx context.Context, c net.Conn) context.Context {
+			p, err := peerOf(c)
+			return context.WithValue(ctx, peerKey{}, peerInfo{peer: p, err: err})
+		},
+	}
+	return h, nil
+}
+
+func (h *HTTPServer) Start() error {
+	var ln net.Listener
+	var err error
+	if path, ok := strings.CutPrefix(h.listen, "unix:"); ok {
+		os.Remove(path)
+		if ln, err = net.Listen("unix", path); err == nil {
+			// The token guards access; the socket itself is open like
+			// the protocol socket.
+			err = os.Chmod(path, 0666)
+		}
+	} else {
+		ln, err = net.Listen("tcp", h.listen)
+	}
+	if err != nil {
+		if ln != nil {
+			ln.Close()
+		}
+		return fmt.Errorf("HTTP API listen on %s: %w", h.listen, err)
+	}
+	h.listener = ln
+
+	go func() {
+		if err := h.srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			h.logger.Error().Err(err).Msg("HTTP API stopped")
+		}
+	}()
+	h.logger.Info().Str("listen", h.listen).Msg("HTTP API listening")
+	return nil
+}
+
+func (h *HTTPServer) Stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	h.srv.Shutdown(ctx)
+	if path, ok := strings.CutPrefix(h.listen, "unix:"); ok {
+		os.Remove(path)
+	}
+}
+
+// endpoint returns a handler that checks the token, builds the command's
+// payload from the request and runs it like a socket request.
+func (h *HTTPServer) endpoint(command string, payload func(*http.Request) ([]byte, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !h.authenticated(r) {
+			writeHTTPError(w, ipc.Errorf(ipc.CodeUnauthorized, "missing or invalid bearer token"))
+			return
+		}
+
+		info, _ := r.Context().Value(peerKey{}).(peerInfo)
+		if ipcErr := h.ipc.authorize(command, info.peer, info.err); ipcErr != nil {
+			writeHTTPError(w, ipcErr)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+		raw, err := payload(r)
+		if err != nil {
+			writeHTTPError(w, ipc.Errorf(ipc.CodeInvalidRequest, "reading request: %v", err))
+			return
+		}
+
+		data, ipcErr := h.ipc.dispatch(command, raw, info.peer, info.err)
+		log := h.logger.Debug().Str("command", command).Str("remote", r.RemoteAddr)
+		if ipcErr != nil {
+			log.Str("code", ipcErr.Code).Msg("HTTP request failed")
+			writeHTTPError(w, ipcErr)
+			return
+		}
+		log.Msg("HTTP request")
+		writeJSON(w, http.StatusOK, data)
+	})
+}
+
+func (h *HTTPServer) authenticated(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) == 1
+}
+
+func noPayload(*http.Request) ([]byte, error) {
+	return nil, nil
+}
+
+// bodyPayload uses the JSON request body as the payload unchanged, so it
+// takes the same fields as the socket protocol.
+func bodyPayload(r *http.Request) ([]byte, error) {
+	return io.ReadAll(r.Body)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeHTTPError(w http.ResponseWriter, e *ipc.Error) {
+	writeJSON(w, httpStatus(e.Code), struct {
+		Error *ipc.Error `json:"error"`
+	}{e})
+}
+
+// httpStatus maps a protocol error code to an HTTP status.
+func httpStatus(code string) int {
+	switch code {
+	case ipc.CodeUnauthorized:
+		return http.StatusUnauthorized
+	case ipc.CodePermissionDenied:
+		return http.StatusForbidden
+	case ipc.CodeNotFound:
+		return http.StatusNotFound
+	case ipc.CodeLocked, ipc.CodeCooldown, ipc.CodeBudgetExhausted:
+		return http.StatusConflict
+	case ipc.CodeInvalidRequest, ipc.CodeInvalidDuration, ipc.CodeInvalidDomain,
+		ipc.CodeInvalidValue, ipc.CodeInvalidConfig:
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// loadToken reads the API token from path, creating the file with a new
+// random token, readable only by its owner, if it does not exist.
+func loadToken(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		token := strings.TrimSpace(string(data))
+		if token == "" {
+			return "", fmt.Errorf("HTTP API token file %s is empty", path)
+		}
+		return token, nil
+	}
+	if !os.IsNotExist(err) {
+		return "", fmt.Errorf("reading HTTP API token: %w", err)
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, []byte(token+"\n"), 0600); err != nil {
+		return "", fmt.Errorf("writing HTTP API token: %w", err)
+	}
+	return token, nil
+}
@@ -0,0 +1,70 @@
+package daemon
+
+import (
+	"errors"
+	"os/user"
+	"strconv"
+
+	"sc/internal/logs"
+)
+
+// peer is the process on the other end of a socket connection, as reported
+// by the kernel rather than claimed by the client.
+type peer struct {
+	uid  int
+	pid  int
+	gids []int
+}
+
+var (
+	// errNoPeerCred is returned where the platform cannot identify callers.
+	errNoPeerCred = errors.New("peer credentials are not supported on this platform")
+	// errNotUnixSocket is returned for connections that carry no
+	// credentials, such as HTTP API requests over TCP.
+	errNotUnixSocket = errors.New("not connected over a unix socket")
+)
+
+// userName returns the peer's login name, or "" if the UID has none.
+func (p *peer) userName() string {
+	u, err := user.LookupId(strconv.Itoa(p.uid))
+	if err != nil {
+		return ""
+	}
+	return u.Username
+}
+
+// groups returns the peer's groups by GID: those the kernel reported plus
+// the user's supplementary groups. Names are "" where a GID has none.
+func (p *peer) groups() map[int]string {
+	gids := append([]int(nil), p.gids...)
+	if u, err := user.LookupId(strconv.Itoa(p.uid)); err == nil {
+		if ids, err := u.GroupIds(); err == nil {
+			for _, id := range ids {
+				if gid, err := strconv.Atoi(id); err == nil {
+					gids = append(gids, gid)
+				}
+			}
+		}
+	}
+
+	groups := make(map[int]string, len(gids))
+	for _, gid := range gids {
+		if _, ok := groups[gid]; ok {
+			continue
+		}
+		name := ""
+		if g, err := user.LookupGroupId(strconv.Itoa(gid)); err == nil {
+			name = g.Name
+		}
+		groups[gid] = name
+	}
+	return groups
+}
+
+// logPeer returns p as recorded in log entries, or nil for an unknown peer.
+func (p *peer) logPeer() *logs.Peer {
+	if p == nil {
+		return nil
+	}
+	return &logs.Peer{UID: p.uid, PID: p.pid, User: p.userName()}
+}
@@ -0,0 +1,47 @@
+//go:build darwin
+
+package daemon
+
+import (
+	"fmt"
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+// peerOf identifies the process connected to conn using LOCAL_PEERCRED and
+// LOCAL_PEERPID.
+func peerOf(conn net.Conn) (*peer, error) {
+	uc, ok := conn.(*net.UnixConn)
+	if !ok {
+		return nil, errNotUnixSocket
+	}
+	raw, err := uc.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+
+	var cred *unix.Xucred
+	var pid int
+	var credErr, pidErr error
+	err = raw.Control(func(fd uintptr) {
+		cred, credErr = unix.GetsockoptXucred(int(fd), unix.SOL_LOCAL, unix.LOCAL_PEERCRED)
+		pid, pidErr = unix.GetsockoptInt(int(fd), unix.SOL_LOCAL, unix.LOCAL_PEERPID)
+	})
+	if err == nil {
+		err = credErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("LOCAL_PEERCRED: %w", err)
+	}
+	if pidErr != nil {
+		return nil, fmt.Errorf("LOCAL_PEERPID: %w", pidErr)
+	}
+
+	p := &peer{uid: int(cred.Uid), pid: pid}
+	n := min(max(int(cred.Ngroups), 0), len(cred.Groups))
+	for _, gid := range cred.Groups[:n] {
+		p.gids = append(p.gids, int(gid))
+	}
+	return p, nil
+}
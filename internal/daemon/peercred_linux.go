@@ -0,0 +1,35 @@
+//go:build linux
+
+package daemon
+
+import (
+	"fmt"
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+// peerOf identifies the process connected to conn using SO_PEERCRED.
+func peerOf(conn net.Conn) (*peer, error) {
+	uc, ok := conn.(*net.UnixConn)
+	if !ok {
+		return nil, errNotUnixSocket
+	}
+	raw, err := uc.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+
+	var cred *unix.Ucred
+	var credErr error
+	err = raw.Control(func(fd uintptr) {
+		cred, credErr = unix.GetsockoptUcred(int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+	})
+	if err == nil {
+		err = credErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("SO_PEERCRED: %w", err)
+	}
+	return &peer{uid: int(cred.Uid), pid: int(cred.Pid), gids: []int{int(cred.Gid)}}, nil
+}
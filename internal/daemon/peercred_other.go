@@ -0,0 +1,9 @@
+//go:build !linux && !darwin
+
+package daemon
+
+import "net"
+
+func peerOf(conn net.Conn) (*peer, error) {
+	return nil, errNoPeerCred
+}
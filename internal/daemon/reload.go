@@ -0,0 +1,142 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"time"
+
+	"sc/internal/config"
+	"sc/internal/ipc"
+	"sc/internal/logs"
+)
+
+// Reload re-reads the config file and switches to it if it is valid. On any
+// error the running config is kept. trigger says what asked for the reload
+// ("sighup", "file", "ipc") and, with by, is only used for logging. check,
+// if set, vets the change for the client that asked for it.
+func (d *Daemon) Reload(trigger string, by *logs.Peer, check accessCheck) (ipc.ReloadData, error) {
+	data, err := os.ReadFile(d.cfgPath)
+	if err == nil {
+		var next *config.Config
+		if next, err = config.Parse(data); err == nil {
+			return d.applyConfig(next, trigger, by, check)
+		}
+	}
+
+	d.logger.Error().Err(err).Str("trigger", trigger).Msg("config reload failed, keeping current config")
+	return ipc.ReloadData{}, fmt.Errorf("config not reloaded: %w", err)
+}
+
+// applyConfig swaps in next, restarting blocking backends and re-detecting
+// DNS flushers if their settings changed. A config that loosens anything
+// currently locked is refused the same way as an edit through the daemon.
+func (d *Daemon) applyConfig(next *config.Config, trigger string, by *logs.Peer, check accessCheck) (ipc.ReloadData, error) {
+	if err := next.Validate(); err != nil {
+		d.logger.Error().Err(err).Str("trigger", trigger).Msg("config reload failed, keeping current config")
+		return ipc.ReloadData{}, fmt.Errorf("config not reloaded: %w", err)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if check != nil {
+		if err := check(d.cfg, next); err != nil {
+			return ipc.ReloadData{}, err
+		}
+	}
+	if err := d.checkLoosening(next, time.Now()); err != nil {
+		d.refuseConfig(err, trigger)
+		return ipc.ReloadData{}, err
+	}
+	return d.swapConfig(next, trigger, by)
+}
+
+// refuseConfig logs and publishes a config that checkLoosening turned down.
+// The file keeps the refused contents; they are picked up by the next
+// reload once nothing forbids them.
+func (d *Daemon) refuseConfig(err *LooseningError, trigger string) {
+	d.logger.Warn().Str("trigger", trigger).Str("reason", err.Reason).Strs("changes", err.Changes).
+		Msg("config reload refused, keeping current config")
+	d.publish(ipc.Event{Type: ipc.EventConfigRefused, Reason: err.Reason, Changes: err.Changes})
+}
+
+// swapConfig switches to next, which must already be validated, and logs
+// the changes as made on behalf of by. The caller holds d.mu.
+func (d *Daemon) swapConfig(next *config.Config, trigger string, by *logs.Peer) (ipc.ReloadData, error) {
+	changes := config.Diff(d.cfg, next)
+	if len(changes) == 0 {
+		return ipc.ReloadData{}, nil
+	}
+
+	prev := d.cfg
+	d.cfg = next
+
+	if !d.fixedBlockers && backendsChanged(prev.Settings, next.Settings) {
+		if err := d.restartBlockers(); err != nil {
+			d.cfg = prev
+			if rerr := d.restartBlockers(); rerr != nil {
+				d.logger.Error().Err(rerr).Msg("failed to restore previous blocking backends")
+			}
+			d.logger.Error().Err(err).Str("trigger", trigger).Msg("config reload failed, keeping current config")
+			return ipc.ReloadData{}, fmt.Errorf("config not reloaded: %w", err)
+		}
+	}
+
+	now := time.Now()
+	if d.focusActive(now) {
+		d.pinConfig()
+		d.saveState()
+	}
+
+	if !reflect.DeepEqual(prev.Settings.DNSFlushers, next.Settings.DNSFlushers) ||
+		!reflect.DeepEqual(prev.Settings.DisabledFlushers, next.Settings.DisabledFlushers) {
+		d.detectFlushers()
+	}
+
+	for _, c := range changes {
+		d.logger.Info().Str("change", c).Msg("config changed")
+	}
+	d.logger.Info().Str("trigger", trigger).Int("changes", len(changes)).Msg("config reloaded")
+	logs.Append(config.LogsPath(), logs.Entry{
+		Timestamp: now,
+		Event:     "config",
+		Reason:    trigger,
+		Changes:   changes,
+		Peer:      by,
+	})
+	d.publish(ipc.Event{Type: ipc.EventConfigReloaded, Reason: trigger, Changes: changes})
+
+	d.trackSchedules(now)
+	d.enforce(now)
+
+	select {
+	case d.reloaded <- struct{}{}:
+	default:
+	}
+	return ipc.ReloadData{Changes: changes}, nil
+}
+
+// backendsChanged reports whether the blocking backends have to be rebuilt
+// to pick up new settings.
+func backendsChanged(old, new config.Settings) bool {
+	return !reflect.DeepEqual(old.Backends, new.Backends) ||
+		!reflect.DeepEqual(old.DNSProxy, new.DNSProxy) ||
+		!reflect.DeepEqual(old.NFTables, new.NFTables)
+}
+
+// restartBlockers stops the current blockers, lifts the blocks of any
+// backend that is no longer configured, and starts fresh ones from d.cfg.
+// The caller holds d.mu.
+func (d *Daemon) restartBlockers() error {
+	d.stopBlockers()
+	for _, b := range d.blockers {
+		if !d.cfg.Settings.UsesBackend(b.Name()) {
+			if err := b.Remove(); err != nil {
+				d.logger.Warn().Err(err).Str("blocker", b.Name()).Msg("failed to remove blocks")
+			}
+		}
+	}
+	d.blockers = nil
+	return d.startBlockers()
+}
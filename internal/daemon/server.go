@@ -4,9 +4,9 @@ import (
 	"bufio"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net"
 	"os"
-	"strings"
 	"time"
 
 	"sc/internal/ipc"
@@ -14,17 +14,29 @@ import (
 	"github.com/rs/zerolog"
 )
 
+// maxRequestSize bounds a single request line, which may carry a whole
+// config file.
+const maxRequestSize = 1 << 20
+
+// eventWriteTimeout bounds how long a subscriber that stopped reading can
+// hold up its stream before it is dropped.
+const eventWriteTimeout = 5 * time.Second
+
 type Server struct {
 	daemon   *Daemon
 	sockPath string
+	version  string
 	logger   zerolog.Logger
 	listener net.Listener
 }
 
-func NewServer(d *Daemon, sockPath string, logger zerolog.Logger) *Server {
+// NewServer returns a server for d on sockPath. version is the daemon's
+// release, reported to clients in the hello handshake.
+func NewServer(d *Daemon, sockPath, version string, logger zerolog.Logger) *Server {
 	return &Server{
 		daemon:   d,
 		sockPath: sockPath,
+		version:  version,
 		logger:   logger,
 	}
 }
@@ -72,129 +84,130 @@ func (s *Server) accept() {
 	}
 }
 
+// handle serves one connection: an optional hello followed by a single
+// request, or a subscription that stays open.
 func (s *Server) handle(conn net.Conn) {
 	defer conn.Close()
 
-	scanner := bufio.NewScanner(conn)
-	if !scanner.Scan() {
-		return
-	}
-
-	var req ipc.Request
-	if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
-		s.writeResponse(conn, ipc.Response{Error: "invalid request"})
-		return
-	}
-
-	var resp ipc.Response
-	switch req.Command {
-	case ipc.CmdStatus:
-		resp = s.handleStatus()
-	case ipc.CmdUnblock:
-		resp = s.handleUnblock(req)
-	case ipc.CmdReblock:
-		resp = s.handleReblock(req)
-	case ipc.CmdAdd:
-		resp = s.handleAdd(req)
-	case ipc.CmdRemove:
-		resp = s.handleRemove(req)
-	case ipc.CmdList:
-		resp = s.handleList()
-	default:
-		resp = ipc.Response{Error: fmt.Sprintf("unknown command: %s", req.Command)}
+	p, peerErr := peerOf(conn)
+	if peerErr != nil {
+		s.logger.Debug().Err(peerErr).Msg("cannot identify client")
 	}
 
-	s.writeResponse(conn, resp)
-}
-
-func (s *Server) handleStatus() ipc.Response {
-	data := s.daemon.Status()
-	return ipc.Response{OK: true, Data: data}
-}
-
-func (s *Server) handleUnblock(req ipc.Request) ipc.Response {
-	durationStr := req.Args["duration"]
-	if durationStr == "" {
-		return ipc.Response{Error: "duration required"}
-	}
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxRequestSize)
+	for scanner.Scan() {
+		var req ipc.Request
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			s.writeResponse(conn, ipc.Response{Error: ipc.Errorf(ipc.CodeInvalidRequest, "invalid request")})
+			return
+		}
 
-	dur, err := time.ParseDuration(durationStr)
-	if err != nil {
-		return ipc.Response{Error: fmt.Sprintf("invalid duration: %s", durationStr)}
-	}
+		if req.Version != ipc.ProtocolVersion {
+			s.refuseVersion(conn, req)
+			return
+		}
 
-	if max := s.daemon.cfg.Settings.MaxUnblockDuration.Duration; max > 0 && dur > max {
-		dur = max
-	}
+		if req.Command == ipc.CmdHello {
+			s.writeResponse(conn, s.ok(req, s.hello()))
+			continue
+		}
 
-	var domains []string
-	if domainsStr := req.Args["domains"]; domainsStr != "" {
-		domains = strings.Split(domainsStr, ",")
-		for i := range domains {
-			domains[i] = strings.TrimSpace(domains[i])
+		log := s.logger.Debug().Str("id", req.ID).Str("command", req.Command)
+		if p != nil {
+			log = log.Int("uid", p.uid).Int("pid", p.pid)
 		}
-		for _, d := range domains {
-			if !s.daemon.cfg.HasDomain(d) {
-				return ipc.Response{Error: fmt.Sprintf("domain %q not in block list", d)}
-			}
+		if ipcErr := s.authorize(req.Command, p, peerErr); ipcErr != nil {
+			s.writeResponse(conn, ipc.Response{ID: req.ID, Error: ipcErr})
+			return
 		}
-	} else {
-		domains = s.daemon.cfg.Domains
-	}
 
-	data := s.daemon.Unblock(domains, dur)
-	return ipc.Response{OK: true, Data: data}
-}
+		if req.Command == ipc.CmdSubscribe {
+			log.Msg("subscribed")
+			s.subscribe(conn, req)
+			return
+		}
 
-func (s *Server) handleReblock(req ipc.Request) ipc.Response {
-	var domains []string
-	if domainsStr := req.Args["domains"]; domainsStr != "" {
-		domains = strings.Split(domainsStr, ",")
-		for i := range domains {
-			domains[i] = strings.TrimSpace(domains[i])
+		data, ipcErr := s.dispatch(req.Command, req.Payload, p, peerErr)
+		if ipcErr != nil {
+			log.Str("code", ipcErr.Code).Msg("request failed")
+			s.writeResponse(conn, ipc.Response{ID: req.ID, Error: ipcErr})
+			return
 		}
+		log.Msg("request")
+		s.writeResponse(conn, s.ok(req, data))
+		return
 	}
-
-	data := s.daemon.Reblock(domains)
-	return ipc.Response{OK: true, Data: data}
 }
 
-func (s *Server) handleAdd(req ipc.Request) ipc.Response {
-	domainsStr := req.Args["domains"]
-	if domainsStr == "" {
-		return ipc.Response{Error: "domains required"}
-	}
-
-	domains := strings.Split(domainsStr, ",")
-	for i := range domains {
-		domains[i] = strings.TrimSpace(domains[i])
-	}
-
-	data := s.daemon.AddDomains(domains)
-	return ipc.Response{OK: true, Data: data}
+func (s *Server) hello() ipc.HelloData {
+	return ipc.HelloData{ProtocolVersion: ipc.ProtocolVersion, DaemonVersion: s.version}
 }
 
-func (s *Server) handleRemove(req ipc.Request) ipc.Response {
-	domainsStr := req.Args["domains"]
-	if domainsStr == "" {
-		return ipc.Response{Error: "domains required"}
+func (s *Server) ok(req ipc.Request, data any) ipc.Response {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return ipc.Response{ID: req.ID, Error: ipc.Errorf(ipc.CodeInternal, "encoding response: %v", err)}
 	}
+	return ipc.Response{ID: req.ID, OK: true, Data: raw}
+}
 
-	domains := strings.Split(domainsStr, ",")
-	for i := range domains {
-		domains[i] = strings.TrimSpace(domains[i])
+// refuseVersion answers a request from a client speaking another protocol
+// version. Clients from before versioning send no version and expect the
+// error as a plain string, so they get that shape.
+func (s *Server) refuseVersion(conn net.Conn, req ipc.Request) {
+	s.logger.Warn().Int("client_protocol", req.Version).Str("command", req.Command).
+		Msg("refusing request from client with a different protocol version")
+
+	if req.Version == 0 {
+		data, _ := json.Marshal(map[string]any{
+			"ok":    false,
+			"error": fmt.Sprintf("this sc CLI is older than the running daemon %s — use the sc binary that matches it", s.version),
+		})
+		conn.Write(append(data, '\n'))
+		return
 	}
 
-	data := s.daemon.RemoveDomains(domains)
-	return ipc.Response{OK: true, Data: data}
+	resp := s.ok(req, s.hello())
+	resp.OK = false
+	resp.Error = ipc.Errorf(ipc.CodeVersionMismatch, "daemon speaks protocol %d, client speaks %d", ipc.ProtocolVersion, req.Version)
+	s.writeResponse(conn, resp)
 }
 
-func (s *Server) handleList() ipc.Response {
-	data := s.daemon.ListDomains()
-	return ipc.Response{OK: true, Data: data}
+// subscribe acknowledges the request and then streams events as
+// newline-delimited JSON until the client disconnects.
+func (s *Server) subscribe(conn net.Conn, req ipc.Request) {
+	events, cancel := s.daemon.Subscribe()
+	defer cancel()
+
+	// The client sends nothing more; a read returning means it hung up.
+	gone := make(chan struct{})
+	go func() {
+		io.Copy(io.Discard, conn)
+		close(gone)
+	}()
+
+	s.writeResponse(conn, ipc.Response{ID: req.ID, OK: true})
+	enc := json.NewEncoder(conn)
+	for {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			conn.SetWriteDeadline(time.Now().Add(eventWriteTimeout))
+			if err := enc.Encode(ev); err != nil {
+				s.logger.Debug().Err(err).Msg("dropping subscriber")
+				return
+			}
+		case <-gone:
+			return
+		}
+	}
 }
 
 func (s *Server) writeResponse(conn net.Conn, resp ipc.Response) {
+	resp.Version = ipc.ProtocolVersion
 	data, _ := json.Marshal(resp)
 	data = append(data, '\n')
 	conn.Write(data)
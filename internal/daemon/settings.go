@@ -0,0 +1,62 @@
+package daemon
+
+import (
+	"fmt"
+	"time"
+
+	"sc/internal/config"
+	"sc/internal/ipc"
+	"sc/internal/logs"
+)
+
+// GetSetting returns the running value of a single config setting.
+func (d *Daemon) GetSetting(key string) (ipc.SettingData, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	value, err := d.cfg.Get(key)
+	if err != nil {
+		return ipc.SettingData{}, err
+	}
+	return ipc.SettingData{Key: key, Value: value}, nil
+}
+
+// SetSetting changes a single config setting on behalf of by, validates the
+// result and applies it immediately, persisting it to the config file. The
+// same loosening and access rules as ApplyConfig apply.
+func (d *Daemon) SetSetting(key, value string, by *logs.Peer, check accessCheck) (ipc.SettingData, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	next, err := d.cfg.Clone()
+	if err != nil {
+		return ipc.SettingData{}, err
+	}
+	if err := next.Set(key, value); err != nil {
+		return ipc.SettingData{}, err
+	}
+	if err := next.Validate(); err != nil {
+		return ipc.SettingData{}, err
+	}
+	if check != nil {
+		if err := check(d.cfg, next); err != nil {
+			return ipc.SettingData{}, err
+		}
+	}
+	if err := d.checkLoosening(next, time.Now()); err != nil {
+		return ipc.SettingData{}, err
+	}
+
+	prev := d.cfg
+	data, err := d.swapConfig(next, "set", by)
+	if err != nil {
+		return ipc.SettingData{}, err
+	}
+	if err := config.Save(next, d.cfgPath); err != nil {
+		d.swapConfig(prev, "rollback", by)
+		return ipc.SettingData{}, fmt.Errorf("writing config: %w", err)
+	}
+
+	value, _ = next.Get(key)
+	return ipc.SettingData{Key: key, Value: value, Changes: data.Changes}, nil
+}
@@ -0,0 +1,137 @@
+package daemon
+
+import (
+	"container/heap"
+	"time"
+
+	"sc/internal/config"
+	"sc/internal/ipc"
+	"sc/internal/logs"
+)
+
+// expiryWarning is how long before an unblock ends subscribers are told.
+const expiryWarning = time.Minute
+
+// expiry is a scheduled reblock, or the warning sent ahead of one. Entries
+// are never removed from the heap when an unblock is extended or ended
+// early; they are skipped when popped if the state no longer holds the same
+// deadline.
+type expiry struct {
+	domain string
+	until  time.Time
+	at     time.Time
+	warn   bool
+}
+
+// timerHeap orders pending timers by firing time, earliest first.
+type timerHeap []expiry
+
+func (h timerHeap) Len() int           { return len(h) }
+func (h timerHeap) Less(i, j int) bool { return h[i].at.Before(h[j].at) }
+func (h timerHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *timerHeap) Push(x any)        { *h = append(*h, x.(expiry)) }
+func (h *timerHeap) Pop() any {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+// scheduleExpiry queues the reblock of domain at until, plus a warning a
+// minute earlier if there is time for one, and wakes Run so it can re-arm
+// its timer. The caller holds d.mu.
+func (d *Daemon) scheduleExpiry(domain string, until time.Time) {
+	heap.Push(&d.timers, expiry{domain: domain, until: until, at: until})
+	if warnAt := until.Add(-expiryWarning); warnAt.After(time.Now()) {
+		heap.Push(&d.timers, expiry{domain: domain, until: until, at: warnAt, warn: true})
+	}
+	select {
+	case d.rearm <- struct{}{}:
+	default:
+	}
+}
+
+// current reports whether e still belongs to the domain's unblock.
+func (d *Daemon) current(e expiry) (UnblockEntry, bool) {
+	entry, ok := d.state.Unblocked[e.domain]
+	return entry, ok && entry.Until.Equal(e.until)
+}
+
+// nextExpiry returns the earliest pending firing time, dropping stale
+// entries on the way.
+func (d *Daemon) nextExpiry() (time.Time, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for d.timers.Len() > 0 {
+		next := d.timers[0]
+		if _, ok := d.current(next); ok {
+			return next.at, true
+		}
+		heap.Pop(&d.timers)
+	}
+	return time.Time{}, false
+}
+
+// expireUnblocks fires every due timer: warnings are published, and
+// domains whose unblock has run out are reblocked. Usage, cooldowns and the
+// log entry use the scheduled deadline rather than the time the timer
+// actually fired.
+func (d *Daemon) expireUnblocks(now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	changed := false
+	for d.timers.Len() > 0 && !d.timers[0].at.After(now) {
+		due := heap.Pop(&d.timers).(expiry)
+		entry, ok := d.current(due)
+		if !ok {
+			continue
+		}
+
+		if due.warn {
+			left := entry.Until.Sub(now)
+			d.publish(ipc.Event{
+				Type:            ipc.EventExpiring,
+				Domains:         []string{due.domain},
+				Duration:        left.Round(time.Second).String(),
+				DurationSeconds: seconds(left),
+			})
+			continue
+		}
+
+		delete(d.state.Unblocked, due.domain)
+		d.recordUsage(due.domain, entry.Started, entry.Until)
+		d.startCooldown(due.domain, entry.Until)
+		changed = true
+		d.logger.Info().Str("domain", due.domain).Time("until", entry.Until).Msg("timer expired, reblocking")
+		logs.Append(config.LogsPath(), logs.Entry{
+			Timestamp: entry.Until,
+			Event:     "reblock",
+			Domain:    due.domain,
+			Reason:    "timer_expired",
+		})
+		d.publish(ipc.Event{
+			Type:    ipc.EventReblocked,
+			Domains: []string{due.domain},
+			Reason:  "timer_expired",
+		})
+	}
+
+	if changed {
+		d.enforce(now)
+		d.saveState()
+	}
+}
+
+// armExpiry sets t to fire at the next pending deadline, or stops it when
+// nothing is unblocked.
+func (d *Daemon) armExpiry(t *time.Timer) {
+	next, ok := d.nextExpiry()
+	if !ok {
+		t.Stop()
+		return
+	}
+	t.Reset(time.Until(next))
+}
@@ -0,0 +1,95 @@
+package daemon
+
+import (
+	"path/filepath"
+	"slices"
+	"time"
+
+	"sc/internal/blocker"
+	"sc/internal/watch"
+)
+
+const (
+	// settleDelay coalesces the burst of notifications a single save
+	// produces (write, rename, attribute change) into one re-apply.
+	settleDelay = 100 * time.Millisecond
+
+	// safetyInterval is how often the tick re-applies the block list when
+	// file notifications are active and nothing has changed in between.
+	safetyInterval = time.Minute
+)
+
+// watchPaths returns the config file and the files the blockers enforce
+// through. The caller holds d.mu.
+func (d *Daemon) watchPaths() []string {
+	paths := []string{d.cfgPath}
+	for _, b := range d.blockers {
+		if wb, ok := b.(blocker.Watched); ok {
+			paths = append(paths, wb.Files()...)
+		}
+	}
+	return paths
+}
+
+// startWatcher watches the files from watchPaths. It returns nil if
+// notifications are unavailable, in which case the tick keeps re-applying
+// on every check interval.
+func (d *Daemon) startWatcher() *watch.Watcher {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	paths := d.watchPaths()
+	d.watched = paths
+
+	w, err := watch.New(paths...)
+	if err != nil {
+		d.watching = false
+		d.logger.Warn().Err(err).Msg("file notifications unavailable, falling back to polling")
+		return nil
+	}
+
+	d.watching = true
+	d.logger.Info().Strs("paths", paths).Msg("watching files for changes")
+	return w
+}
+
+// restartWatcher replaces w if the blockers now enforce through different
+// files, e.g. after a reload switched backends.
+func (d *Daemon) restartWatcher(w *watch.Watcher) *watch.Watcher {
+	d.mu.RLock()
+	same := slices.Equal(d.watchPaths(), d.watched)
+	d.mu.RUnlock()
+	if same && w != nil {
+		return w
+	}
+
+	if w != nil {
+		w.Close()
+	}
+	return d.startWatcher()
+}
+
+// filesChanged reloads the config if it was modified and re-applies the
+// block list straight away.
+func (d *Daemon) filesChanged(paths map[string]bool) {
+	cfgPath, err := filepath.Abs(d.cfgPath)
+	if err == nil && paths[cfgPath] {
+		d.Reload("file", nil, nil)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for path := range paths {
+		d.logger.Debug().Str("path", path).Msg("watched file changed")
+	}
+	d.enforce(time.Now())
+}
+
+// enforce applies the block list through every blocker and verifies it.
+// The caller holds d.mu.
+func (d *Daemon) enforce(now time.Time) {
+	d.applyAndFlush()
+	d.verify(now)
+	d.lastEnforced = now
+}
@@ -1,12 +1,162 @@
 package dns
 
-import "os/exec"
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
 
-func Flush() error {
-	// OS-level DNS cache — covers different macOS versions
-	exec.Command("dscacheutil", "-flushcache").Run()
-	exec.Command("killall", "-HUP", "mDNSResponder").Run()
+// Flusher clears one kind of OS-level DNS cache.
+type Flusher interface {
+	Name() string
+	Available() bool
+	Flush() error
+}
+
+var registry = []Flusher{
+	macOS{},
+	resolved{},
+	nscd{},
+	dnsmasq{},
+}
+
+// Names returns the names of all known flushers.
+func Names() []string {
+	names := make([]string, len(registry))
+	for i, f := range registry {
+		names[i] = f.Name()
+	}
+	return names
+}
+
+// Detect returns the flushers to use. With no explicit selection every
+// available flusher is used; disabled names are always skipped.
+func Detect(selected, disabled []string) ([]Flusher, error) {
+	skip := make(map[string]bool)
+	for _, name := range disabled {
+		if lookup(name) == nil {
+			return nil, fmt.Errorf("unknown DNS flusher %q", name)
+		}
+		skip[name] = true
+	}
+
+	var result []Flusher
+	if len(selected) > 0 {
+		for _, name := range selected {
+			f := lookup(name)
+			if f == nil {
+				return nil, fmt.Errorf("unknown DNS flusher %q", name)
+			}
+			if !skip[name] {
+				result = append(result, f)
+			}
+		}
+		return result, nil
+	}
+
+	for _, f := range registry {
+		if !skip[f.Name()] && f.Available() {
+			result = append(result, f)
+		}
+	}
+	return result, nil
+}
+
+func lookup(name string) Flusher {
+	for _, f := range registry {
+		if f.Name() == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// Flush runs every flusher and joins their errors.
+func Flush(flushers []Flusher) error {
+	var errs []error
+	for _, f := range flushers {
+		if err := f.Flush(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", f.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+type macOS struct{}
+
+func (macOS) Name() string    { return "macos" }
+func (macOS) Available() bool { return runtime.GOOS == "darwin" }
+
+func (macOS) Flush() error {
+	if err := run("dscacheutil", "-flushcache"); err != nil {
+		return err
+	}
+	if err := run("killall", "-HUP", "mDNSResponder"); err != nil {
+		return err
+	}
+	// Only present on older macOS versions
 	exec.Command("killall", "mDNSResponderHelper").Run()
+	return nil
+}
+
+type resolved struct{}
+
+func (resolved) Name() string { return "systemd-resolved" }
 
+func (resolved) Available() bool {
+	if _, err := os.Stat("/run/systemd/resolve"); err != nil {
+		return false
+	}
+	return hasBinary("resolvectl")
+}
+
+func (resolved) Flush() error {
+	return run("resolvectl", "flush-caches")
+}
+
+type nscd struct{}
+
+func (nscd) Name() string { return "nscd" }
+
+func (nscd) Available() bool {
+	return hasBinary("nscd") && isRunning("nscd")
+}
+
+func (nscd) Flush() error {
+	return run("nscd", "-i", "hosts")
+}
+
+type dnsmasq struct{}
+
+func (dnsmasq) Name() string { return "dnsmasq" }
+
+func (dnsmasq) Available() bool {
+	return isRunning("dnsmasq")
+}
+
+func (dnsmasq) Flush() error {
+	return run("pkill", "-HUP", "-x", "dnsmasq")
+}
+
+func run(name string, args ...string) error {
+	out, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("%s: %w: %s", name, err, msg)
+		}
+		return fmt.Errorf("%s: %w", name, err)
+	}
 	return nil
 }
+
+func hasBinary(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
+
+func isRunning(process string) bool {
+	return exec.Command("pgrep", "-x", process).Run() == nil
+}
@@ -0,0 +1,139 @@
+package dnsproxy
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Lookup queries upstream directly for the A and AAAA records of name. Unlike
+// the system resolver it never consults the hosts file, so it sees through
+// sc's own blocks.
+func Lookup(upstream, name string) ([]net.IP, error) {
+	var ips []net.IP
+	var lastErr error
+	for _, qtype := range []uint16{typeA, typeAAAA} {
+		query, err := buildQuery(name, qtype)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := exchange("udp", upstream, query)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		found, err := parseAnswers(resp, qtype)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		ips = append(ips, found...)
+	}
+	if len(ips) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+	return ips, nil
+}
+
+// LookupAny tries each upstream in turn until one answers.
+func LookupAny(upstreams []string, name string) ([]net.IP, error) {
+	var lastErr error = fmt.Errorf("no upstreams configured")
+	for _, upstream := range upstreams {
+		ips, err := Lookup(upstream, name)
+		if err == nil {
+			return ips, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+func buildQuery(name string, qtype uint16) ([]byte, error) {
+	msg := make([]byte, headerLen, 512)
+	var id [2]byte
+	rand.Read(id[:])
+	copy(msg[0:2], id[:])
+	msg[2] = 0x01                           // RD
+	binary.BigEndian.PutUint16(msg[4:6], 1) // QDCOUNT
+
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		if len(label) == 0 || len(label) > 63 {
+			return nil, fmt.Errorf("invalid name %q", name)
+		}
+		msg = append(msg, byte(len(label)))
+		msg = append(msg, label...)
+	}
+	msg = append(msg, 0)
+	msg = binary.BigEndian.AppendUint16(msg, qtype)
+	msg = binary.BigEndian.AppendUint16(msg, classIN)
+	return msg, nil
+}
+
+// parseAnswers returns the addresses of the given type in a response's
+// answer section.
+func parseAnswers(msg []byte, qtype uint16) ([]net.IP, error) {
+	if len(msg) < headerLen {
+		return nil, errMalformed
+	}
+	if rcode := msg[3] & 0x0F; rcode != rcodeNoError {
+		if rcode == rcodeNXDomain {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("DNS error rcode %d", rcode)
+	}
+
+	qdcount := int(binary.BigEndian.Uint16(msg[4:6]))
+	ancount := int(binary.BigEndian.Uint16(msg[6:8]))
+
+	off := headerLen
+	for i := 0; i < qdcount; i++ {
+		var err error
+		if off, err = skipName(msg, off); err != nil {
+			return nil, err
+		}
+		off += 4
+	}
+
+	var ips []net.IP
+	for i := 0; i < ancount; i++ {
+		var err error
+		if off, err = skipName(msg, off); err != nil {
+			return nil, err
+		}
+		if off+10 > len(msg) {
+			return nil, errMalformed
+		}
+		rtype := binary.BigEndian.Uint16(msg[off : off+2])
+		rdlen := int(binary.BigEndian.Uint16(msg[off+8 : off+10]))
+		off += 10
+		if off+rdlen > len(msg) {
+			return nil, errMalformed
+		}
+		if rtype == qtype && (rdlen == net.IPv4len || rdlen == net.IPv6len) {
+			ips = append(ips, net.IP(append([]byte(nil), msg[off:off+rdlen]...)))
+		}
+		off += rdlen
+	}
+	return ips, nil
+}
+
+// skipName returns the offset just past the (possibly compressed) name at off.
+func skipName(msg []byte, off int) (int, error) {
+	for {
+		if off >= len(msg) {
+			return 0, errMalformed
+		}
+		n := int(msg[off])
+		switch {
+		case n == 0:
+			return off + 1, nil
+		case n&0xC0 == 0xC0:
+			return off + 2, nil
+		case n&0xC0 != 0:
+			return 0, errMalformed
+		}
+		off += 1 + n
+	}
+}
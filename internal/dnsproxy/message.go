@@ -0,0 +1,109 @@
+package dnsproxy
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+)
+
+const (
+	headerLen = 12
+
+	typeA    = 1
+	typeAAAA = 28
+	classIN  = 1
+
+	rcodeNoError  = 0
+	rcodeServFail = 2
+	rcodeNXDomain = 3
+
+	blockTTL = 60
+)
+
+var errMalformed = errors.New("malformed DNS message")
+
+type question struct {
+	name  string
+	qtype uint16
+	// end is the offset just past the question section's first entry.
+	end int
+}
+
+// parseQuestion extracts the first question from a DNS query. Compression
+// pointers are not expected in queries and are rejected.
+func parseQuestion(msg []byte) (question, error) {
+	if len(msg) < headerLen || binary.BigEndian.Uint16(msg[4:6]) == 0 {
+		return question{}, errMalformed
+	}
+
+	var labels []string
+	off := headerLen
+	for {
+		if off >= len(msg) {
+			return question{}, errMalformed
+		}
+		n := int(msg[off])
+		off++
+		if n == 0 {
+			break
+		}
+		if n&0xC0 != 0 || off+n > len(msg) {
+			return question{}, errMalformed
+		}
+		labels = append(labels, string(msg[off:off+n]))
+		off += n
+	}
+
+	if off+4 > len(msg) {
+		return question{}, errMalformed
+	}
+	return question{
+		name:  strings.ToLower(strings.Join(labels, ".")),
+		qtype: binary.BigEndian.Uint16(msg[off : off+2]),
+		end:   off + 4,
+	}, nil
+}
+
+// blockedResponse answers A and AAAA queries with the unspecified address
+// and every other type with an empty NOERROR response, mirroring what the
+// hosts file backend does for blocked names.
+func blockedResponse(query []byte, q question) []byte {
+	var rdata []byte
+	switch q.qtype {
+	case typeA:
+		rdata = make([]byte, 4)
+	case typeAAAA:
+		rdata = make([]byte, 16)
+	}
+
+	resp := reply(query, q, rcodeNoError)
+	if rdata == nil {
+		return resp
+	}
+
+	binary.BigEndian.PutUint16(resp[6:8], 1) // ANCOUNT
+	answer := make([]byte, 12, 12+len(rdata))
+	binary.BigEndian.PutUint16(answer[0:2], 0xC000|headerLen) // pointer to question name
+	binary.BigEndian.PutUint16(answer[2:4], q.qtype)
+	binary.BigEndian.PutUint16(answer[4:6], classIN)
+	binary.BigEndian.PutUint32(answer[6:10], blockTTL)
+	binary.BigEndian.PutUint16(answer[10:12], uint16(len(rdata)))
+	answer = append(answer, rdata...)
+	return append(resp, answer...)
+}
+
+// reply builds a response header and question echo for query with the given
+// response code and no records.
+func reply(query []byte, q question, rcode byte) []byte {
+	resp := make([]byte, q.end)
+	copy(resp, query[:q.end])
+
+	// QR=1, keep opcode and RD, set RA, clear everything else.
+	resp[2] = 0x80 | (query[2] & 0x79)
+	resp[3] = 0x80 | (rcode & 0x0F)
+	binary.BigEndian.PutUint16(resp[4:6], 1)  // QDCOUNT
+	binary.BigEndian.PutUint16(resp[6:8], 0)  // ANCOUNT
+	binary.BigEndian.PutUint16(resp[8:10], 0) // NSCOUNT
+	binary.BigEndian.PutUint16(resp[10:12], 0)
+	return resp
+}
@@ -0,0 +1,325 @@
+package dnsproxy
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"sc/internal/blocker"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	upstreamTimeout = 3 * time.Second
+	maxUDPSize      = 4096
+)
+
+// DefaultUpstreams is used when none are configured and none can be read
+// from resolv.conf.
+var DefaultUpstreams = []string{"1.1.1.1:53", "8.8.8.8:53"}
+
+// Server is a small forwarding DNS resolver. Names equal to or under a
+// blocked domain are answered locally; everything else is relayed to the
+// upstream resolvers unchanged.
+type Server struct {
+	listen    string
+	upstreams []string
+	logger    zerolog.Logger
+
+	mu      sync.RWMutex
+	blocked map[string]bool
+
+	udp *net.UDPConn
+	tcp net.Listener
+	wg  sync.WaitGroup
+}
+
+func New(listen string, upstreams []string, logger zerolog.Logger) *Server {
+	return &Server{
+		listen:    listen,
+		upstreams: upstreams,
+		logger:    logger,
+		blocked:   make(map[string]bool),
+	}
+}
+
+// Addr returns the UDP address the server is bound to.
+func (s *Server) Addr() string {
+	if s.udp == nil {
+		return s.listen
+	}
+	return s.udp.LocalAddr().String()
+}
+
+func (s *Server) Name() string { return "dns" }
+
+func (s *Server) Apply(entries []blocker.Entry) (bool, error) {
+	return s.SetBlocked(blocker.Names(entries)), nil
+}
+
+func (s *Server) Remove() error {
+	s.SetBlocked(nil)
+	return nil
+}
+
+func (s *Server) Verify(entries []blocker.Entry) error {
+	if s.udp == nil {
+		return fmt.Errorf("DNS proxy is not running")
+	}
+	for _, name := range blocker.Names(entries) {
+		if !s.IsBlocked(name) {
+			return fmt.Errorf("DNS proxy is not blocking %s", name)
+		}
+	}
+	return nil
+}
+
+// SetBlocked replaces the set of blocked domains and reports whether it
+// differs from the previous one.
+func (s *Server) SetBlocked(domains []string) bool {
+	blocked := make(map[string]bool, len(domains))
+	for _, d := range domains {
+		blocked[strings.ToLower(strings.TrimSuffix(d, "."))] = true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	changed := len(blocked) != len(s.blocked)
+	for d := range blocked {
+		if !s.blocked[d] {
+			changed = true
+			break
+		}
+	}
+	s.blocked = blocked
+	return changed
+}
+
+// IsBlocked reports whether name is a blocked domain or any subdomain of one.
+func (s *Server) IsBlocked(name string) bool {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for {
+		if s.blocked[name] {
+			return true
+		}
+		i := strings.IndexByte(name, '.')
+		if i == -1 {
+			return false
+		}
+		name = name[i+1:]
+	}
+}
+
+func (s *Server) Start() error {
+	addr, err := net.ResolveUDPAddr("udp", s.listen)
+	if err != nil {
+		return fmt.Errorf("resolve %s: %w", s.listen, err)
+	}
+	udp, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return fmt.Errorf("listen udp %s: %w", s.listen, err)
+	}
+
+	// Bind TCP to the same port UDP ended up on (matters when listen uses :0).
+	tcp, err := net.Listen("tcp", udp.LocalAddr().String())
+	if err != nil {
+		udp.Close()
+		return fmt.Errorf("listen tcp %s: %w", s.listen, err)
+	}
+
+	s.udp = udp
+	s.tcp = tcp
+
+	s.wg.Add(2)
+	go s.serveUDP()
+	go s.serveTCP()
+
+	s.logger.Info().Str("listen", s.Addr()).Strs("upstreams", s.upstreams).Msg("DNS proxy listening")
+	return nil
+}
+
+func (s *Server) Stop() {
+	if s.udp != nil {
+		s.udp.Close()
+	}
+	if s.tcp != nil {
+		s.tcp.Close()
+	}
+	s.wg.Wait()
+}
+
+func (s *Server) serveUDP() {
+	defer s.wg.Done()
+
+	buf := make([]byte, maxUDPSize)
+	for {
+		n, client, err := s.udp.ReadFromUDP(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+
+		query := append([]byte(nil), buf[:n]...)
+		go func() {
+			if resp := s.resolve(query, "udp"); resp != nil {
+				s.udp.WriteToUDP(resp, client)
+			}
+		}()
+	}
+}
+
+func (s *Server) serveTCP() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.tcp.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		go s.handleTCP(conn)
+	}
+}
+
+func (s *Server) handleTCP(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	for {
+		conn.SetDeadline(time.Now().Add(10 * time.Second))
+		query, err := readTCPMessage(r)
+		if err != nil {
+			return
+		}
+		resp := s.resolve(query, "tcp")
+		if resp == nil {
+			return
+		}
+		if err := writeTCPMessage(conn, resp); err != nil {
+			return
+		}
+	}
+}
+
+// resolve answers a blocked query locally or relays it upstream over the
+// same transport it arrived on. A nil result means no answer should be sent.
+func (s *Server) resolve(query []byte, network string) []byte {
+	q, err := parseQuestion(query)
+	if err != nil {
+		return s.forward(query, network)
+	}
+
+	if s.IsBlocked(q.name) {
+		s.logger.Debug().Str("name", q.name).Msg("blocked DNS query")
+		// Firefox only turns default DoH off when its canary does not resolve.
+		if q.name == blocker.FirefoxCanary {
+			return reply(query, q, rcodeNXDomain)
+		}
+		return blockedResponse(query, q)
+	}
+
+	resp := s.forward(query, network)
+	if resp == nil {
+		return reply(query, q, rcodeServFail)
+	}
+	return resp
+}
+
+func (s *Server) forward(query []byte, network string) []byte {
+	for _, upstream := range s.upstreams {
+		resp, err := exchange(network, upstream, query)
+		if err == nil {
+			return resp
+		}
+		s.logger.Debug().Err(err).Str("upstream", upstream).Msg("DNS upstream failed")
+	}
+	return nil
+}
+
+func exchange(network, upstream string, query []byte) ([]byte, error) {
+	conn, err := net.DialTimeout(network, upstream, upstreamTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(upstreamTimeout))
+
+	if network == "tcp" {
+		if err := writeTCPMessage(conn, query); err != nil {
+			return nil, err
+		}
+		return readTCPMessage(bufio.NewReader(conn))
+	}
+
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, maxUDPSize)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+func readTCPMessage(r io.Reader) ([]byte, error) {
+	var size [2]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return nil, err
+	}
+	msg := make([]byte, binary.BigEndian.Uint16(size[:]))
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func writeTCPMessage(w io.Writer, msg []byte) error {
+	buf := make([]byte, 2, 2+len(msg))
+	binary.BigEndian.PutUint16(buf, uint16(len(msg)))
+	_, err := w.Write(append(buf, msg...))
+	return err
+}
+
+// SystemUpstreams returns the system's nameservers, skipping the proxy's own
+// listen address so it never forwards to itself. With systemd-resolved the
+// real upstreams are used rather than its local stub, which would answer
+// from the hosts file.
+func SystemUpstreams(listen string) []string {
+	data, err := os.ReadFile("/run/systemd/resolve/resolv.conf")
+	if err != nil {
+		data, err = os.ReadFile("/etc/resolv.conf")
+	}
+	if err != nil {
+		return nil
+	}
+
+	self, _, _ := net.SplitHostPort(listen)
+
+	var result []string
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "nameserver" || fields[1] == self {
+			continue
+		}
+		result = append(result, net.JoinHostPort(fields[1], "53"))
+	}
+	return result
+}
@@ -0,0 +1,256 @@
+package dnsproxy
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+
+	"sc/internal/blocker"
+
+	"github.com/rs/zerolog"
+)
+
+var (
+	upstreamA    = net.ParseIP("192.0.2.1").To4()
+	upstreamAAAA = net.ParseIP("2001:db8::1")
+)
+
+// fakeUpstream is a resolver on 127.0.0.1 that answers every A and AAAA
+// query with a fixed documentation address and records what it was asked.
+type fakeUpstream struct {
+	addr string
+
+	mu      sync.Mutex
+	queries [][]byte
+}
+
+func startUpstream(t *testing.T) *fakeUpstream {
+	t.Helper()
+
+	udp, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tcp, err := net.Listen("tcp", udp.LocalAddr().String())
+	if err != nil {
+		udp.Close()
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		udp.Close()
+		tcp.Close()
+	})
+
+	u := &fakeUpstream{addr: udp.LocalAddr().String()}
+	go func() {
+		buf := make([]byte, maxUDPSize)
+		for {
+			n, client, err := udp.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			udp.WriteToUDP(u.answer(buf[:n]), client)
+		}
+	}()
+	go func() {
+		for {
+			conn, err := tcp.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				query, err := readTCPMessage(bufio.NewReader(conn))
+				if err != nil {
+					return
+				}
+				writeTCPMessage(conn, u.answer(query))
+			}()
+		}
+	}()
+	return u
+}
+
+func (u *fakeUpstream) answer(query []byte) []byte {
+	u.mu.Lock()
+	u.queries = append(u.queries, append([]byte(nil), query...))
+	u.mu.Unlock()
+
+	q, err := parseQuestion(query)
+	if err != nil {
+		return nil
+	}
+	// Reuse the blocked answer layout and swap in a real address.
+	resp := blockedResponse(query, q)
+	switch q.qtype {
+	case typeA:
+		copy(resp[len(resp)-net.IPv4len:], upstreamA)
+	case typeAAAA:
+		copy(resp[len(resp)-net.IPv6len:], upstreamAAAA)
+	}
+	return resp
+}
+
+func (u *fakeUpstream) asked() [][]byte {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.queries
+}
+
+func startServer(t *testing.T, upstreams ...string) *Server {
+	t.Helper()
+
+	s := New("127.0.0.1:0", upstreams, zerolog.Nop())
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Stop)
+
+	entries := append([]blocker.Entry{
+		{Domain: "example.com", Hosts: []string{"example.com"}},
+	}, blocker.DoHEntries()...)
+	if _, err := s.Apply(entries); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestServerBlocks(t *testing.T) {
+	upstream := startUpstream(t)
+	s := startServer(t, upstream.addr)
+
+	tests := []struct {
+		name  string
+		qtype uint16
+		rcode byte
+		want  net.IP
+	}{
+		{"example.com", typeA, rcodeNoError, net.IPv4zero.To4()},
+		{"example.com", typeAAAA, rcodeNoError, net.IPv6unspecified},
+		{"www.example.com", typeA, rcodeNoError, net.IPv4zero.To4()},
+		{"Deep.Sub.EXAMPLE.com.", typeAAAA, rcodeNoError, net.IPv6unspecified},
+		{blocker.FirefoxCanary, typeA, rcodeNXDomain, nil},
+	}
+
+	for _, network := range []string{"udp", "tcp"} {
+		for _, tt := range tests {
+			t.Run(network+"/"+tt.name, func(t *testing.T) {
+				query, err := buildQuery(tt.name, tt.qtype)
+				if err != nil {
+					t.Fatal(err)
+				}
+				resp, err := exchange(network, s.Addr(), query)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if !bytes.Equal(resp[0:2], query[0:2]) {
+					t.Errorf("response ID %x, want %x", resp[0:2], query[0:2])
+				}
+				if rcode := resp[3] & 0x0F; rcode != tt.rcode {
+					t.Fatalf("rcode = %d, want %d", rcode, tt.rcode)
+				}
+				ips, err := parseAnswers(resp, tt.qtype)
+				if err != nil {
+					t.Fatal(err)
+				}
+				switch {
+				case tt.want == nil && len(ips) != 0:
+					t.Errorf("answers = %v, want none", ips)
+				case tt.want != nil && (len(ips) != 1 || !ips[0].Equal(tt.want)):
+					t.Errorf("answers = %v, want [%v]", ips, tt.want)
+				}
+			})
+		}
+	}
+
+	if n := len(upstream.asked()); n != 0 {
+		t.Errorf("upstream received %d queries for blocked names", n)
+	}
+}
+
+func TestServerForwards(t *testing.T) {
+	upstream := startUpstream(t)
+	s := startServer(t, upstream.addr)
+
+	// notexample.com shares a suffix with a blocked domain but is not
+	// under it.
+	names := []string{"golang.org", "notexample.com", "example.com.evil.net"}
+
+	for _, network := range []string{"udp", "tcp"} {
+		for _, name := range names {
+			t.Run(network+"/"+name, func(t *testing.T) {
+				before := len(upstream.asked())
+
+				query, err := buildQuery(name, typeA)
+				if err != nil {
+					t.Fatal(err)
+				}
+				resp, err := exchange(network, s.Addr(), query)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				asked := upstream.asked()
+				if len(asked) != before+1 {
+					t.Fatalf("upstream received %d queries, want 1", len(asked)-before)
+				}
+				if got := asked[len(asked)-1]; !bytes.Equal(got, query) {
+					t.Errorf("upstream received %x, want the query unchanged: %x", got, query)
+				}
+				if want := upstream.answer(query); !bytes.Equal(resp, want) {
+					t.Errorf("response %x, want the upstream's unchanged: %x", resp, want)
+				}
+			})
+		}
+	}
+}
+
+func TestServerUpstreamDown(t *testing.T) {
+	// Grab a free port and release it so nothing answers there.
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dead := conn.LocalAddr().String()
+	conn.Close()
+
+	s := startServer(t, dead)
+
+	query, err := buildQuery("golang.org", typeA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := exchange("udp", s.Addr(), query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rcode := resp[3] & 0x0F; rcode != rcodeServFail {
+		t.Errorf("rcode = %d, want SERVFAIL", rcode)
+	}
+}
+
+func TestParseQuestionMalformed(t *testing.T) {
+	valid, err := buildQuery("example.com", typeA)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string][]byte{
+		"short header":     valid[:headerLen-1],
+		"no questions":     append(append([]byte(nil), valid[:4]...), make([]byte, headerLen-4)...),
+		"truncated label":  valid[:headerLen+3],
+		"missing type":     valid[:len(valid)-3],
+		"compressed label": append(append([]byte(nil), valid[:headerLen]...), 0xC0, 0x0C, 0, 1, 0, 1),
+	}
+	for name, msg := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := parseQuestion(msg); !errors.Is(err, errMalformed) {
+				t.Errorf("parseQuestion = %v, want errMalformed", err)
+			}
+		})
+	}
+}
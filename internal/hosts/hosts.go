@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"strings"
+
+	"sc/internal/blocker"
 )
 
 const (
@@ -16,8 +18,26 @@ var legacyMarkers = [][2]string{
 	{"# ---- BEGIN SC BLOCK ----", "# ---- END SC BLOCK ----"},
 }
 
-func Apply(domains []string, unblocked map[string]bool, blockSubdomains bool) (bool, error) {
-	content, err := os.ReadFile(hostsPath)
+// Blocker enforces blocks with a marked section of the hosts file. Content
+// outside the markers is never touched.
+type Blocker struct {
+	path string
+
+	// last is the block section as sc last wrote or saw it; any other
+	// content found there on the next Apply was put there by someone else.
+	last    string
+	seen    bool
+	tampers []blocker.Tamper
+}
+
+func New() *Blocker {
+	return &Blocker{path: hostsPath}
+}
+
+func (b *Blocker) Name() string { return "hosts" }
+
+func (b *Blocker) Apply(entries []blocker.Entry) (bool, error) {
+	content, err := os.ReadFile(b.path)
 	if err != nil {
 		return false, fmt.Errorf("reading hosts file: %w", err)
 	}
@@ -26,51 +46,54 @@ func Apply(domains []string, unblocked map[string]bool, blockSubdomains bool) (b
 	cleaned := stripLegacyBlocks(original)
 	before, after := splitAroundMarkers(cleaned)
 
-	var groups []string
-	for _, d := range domains {
-		if unblocked[d] {
-			continue
-		}
-		var entry []string
-		entry = append(entry, fmt.Sprintf("0.0.0.0 %s", d))
-		entry = append(entry, fmt.Sprintf("::      %s", d))
-		if blockSubdomains {
-			entry = append(entry, fmt.Sprintf("0.0.0.0 www.%s", d))
-			entry = append(entry, fmt.Sprintf("::      www.%s", d))
-		}
-		groups = append(groups, strings.Join(entry, "\n"))
-	}
+	block := render(entries)
+	newContent := before + block + after
 
-	var block string
-	if len(groups) > 0 {
-		block = beginMarker + "\n" + strings.Join(groups, "\n\n") + "\n" + endMarker + "\n"
+	b.tampers = nil
+	current := currentBlock(original)
+	if b.seen && current != b.last {
+		b.tampers = append(b.tampers, blocker.Tamper{
+			Detail: tamperDetail(original, b.last),
+			Diff:   lineDiff(b.last, current),
+		})
 	}
-
-	newContent := before + block + after
+	// Until a write succeeds the file still holds current, so a failed
+	// write is not mistaken for tampering on the next Apply.
+	b.last, b.seen = current, true
 
 	if newContent == original {
+		b.last = block
 		return false, nil
 	}
 
-	info, err := os.Stat(hostsPath)
+	info, err := os.Stat(b.path)
 	if err != nil {
 		return false, err
 	}
 
-	tmp := hostsPath + ".sc.tmp"
+	tmp := b.path + ".sc.tmp"
 	if err := os.WriteFile(tmp, []byte(newContent), info.Mode()); err != nil {
 		return false, fmt.Errorf("writing temp hosts: %w", err)
 	}
-	if err := os.Rename(tmp, hostsPath); err != nil {
+	if err := os.Rename(tmp, b.path); err != nil {
 		os.Remove(tmp)
 		return false, fmt.Errorf("renaming hosts: %w", err)
 	}
 
+	b.last = block
 	return true, nil
 }
 
-func Remove() error {
-	content, err := os.ReadFile(hostsPath)
+func (b *Blocker) Files() []string {
+	return []string{b.path}
+}
+
+func (b *Blocker) Tampers() []blocker.Tamper {
+	return b.tampers
+}
+
+func (b *Blocker) Remove() error {
+	content, err := os.ReadFile(b.path)
 	if err != nil {
 		return err
 	}
@@ -81,15 +104,121 @@ func Remove() error {
 	newContent := before + after
 
 	if newContent == original {
+		b.last = ""
 		return nil
 	}
 
-	info, err := os.Stat(hostsPath)
+	info, err := os.Stat(b.path)
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(hostsPath, []byte(newContent), info.Mode())
+	if err := os.WriteFile(b.path, []byte(newContent), info.Mode()); err != nil {
+		return err
+	}
+	b.last = ""
+	return nil
+}
+
+func (b *Blocker) Verify(entries []blocker.Entry) error {
+	content, err := os.ReadFile(b.path)
+	if err != nil {
+		return fmt.Errorf("reading hosts file: %w", err)
+	}
+
+	want := render(entries)
+	if got := currentBlock(string(content)); got != want {
+		return fmt.Errorf("hosts block section does not match the block list")
+	}
+	return nil
+}
+
+func tamperDetail(content, expected string) string {
+	hasBegin := strings.Contains(content, beginMarker)
+	hasEnd := strings.Contains(content, endMarker)
+	switch {
+	case !hasBegin && !hasEnd && expected != "":
+		return "block section removed"
+	case hasBegin != hasEnd:
+		return "block markers removed"
+	case expected == "":
+		return "block section added"
+	default:
+		return "block section modified"
+	}
+}
+
+// lineDiff lists lines only in old as "-" and lines only in new as "+".
+func lineDiff(old, new string) string {
+	oldLines := strings.Split(strings.TrimSuffix(old, "\n"), "\n")
+	newLines := strings.Split(strings.TrimSuffix(new, "\n"), "\n")
+
+	inOld := make(map[string]int)
+	for _, l := range oldLines {
+		inOld[l]++
+	}
+	inNew := make(map[string]int)
+	for _, l := range newLines {
+		inNew[l]++
+	}
+
+	var diff []string
+	for _, l := range oldLines {
+		if inNew[l] > 0 {
+			inNew[l]--
+			continue
+		}
+		if l != "" {
+			diff = append(diff, "-"+l)
+		}
+	}
+	for _, l := range newLines {
+		if inOld[l] > 0 {
+			inOld[l]--
+			continue
+		}
+		if l != "" {
+			diff = append(diff, "+"+l)
+		}
+	}
+	return strings.Join(diff, "\n")
+}
+
+// Remove strips the sc block section from the system hosts file.
+func Remove() error {
+	return New().Remove()
+}
+
+// render builds the marked block section for entries, or "" when empty.
+func render(entries []blocker.Entry) string {
+	var groups []string
+	for _, e := range entries {
+		var lines []string
+		for _, name := range e.Hosts {
+			lines = append(lines, fmt.Sprintf("0.0.0.0 %s", name))
+			lines = append(lines, fmt.Sprintf("::      %s", name))
+		}
+		groups = append(groups, strings.Join(lines, "\n"))
+	}
+
+	if len(groups) == 0 {
+		return ""
+	}
+	return beginMarker + "\n" + strings.Join(groups, "\n\n") + "\n" + endMarker + "\n"
+}
+
+// currentBlock returns the marked section of content as render would have
+// produced it, or "" if there is none.
+func currentBlock(content string) string {
+	beginIdx := strings.Index(content, beginMarker)
+	if beginIdx == -1 {
+		return ""
+	}
+	endIdx := strings.Index(content, endMarker)
+	if endIdx == -1 || endIdx < beginIdx {
+		return content[beginIdx:]
+	}
+	return content[beginIdx:endIdx+len(endMarker)] + "\n"
 }
 
 func stripLegacyBlocks(content string) string {
@@ -1,50 +0,0 @@
-package ipc
-
-import (
-	"bufio"
-	"encoding/json"
-	"fmt"
-	"net"
-	"time"
-)
-
-type Client struct {
-	sockPath string
-}
-
-func NewClient(sockPath string) *Client {
-	return &Client{sockPath: sockPath}
-}
-
-func (c *Client) Send(req Request) (*Response, error) {
-	conn, err := net.DialTimeout("unix", c.sockPath, 2*time.Second)
-	if err != nil {
-		return nil, fmt.Errorf("cannot connect to daemon — is it running? Try: sudo sc install")
-	}
-	defer conn.Close()
-
-	conn.SetDeadline(time.Now().Add(5 * time.Second))
-
-	data, err := json.Marshal(req)
-	if err != nil {
-		return nil, err
-	}
-	data = append(data, '\n')
-	if _, err := conn.Write(data); err != nil {
-		return nil, err
-	}
-
-	scanner := bufio.NewScanner(conn)
-	if !scanner.Scan() {
-		if err := scanner.Err(); err != nil {
-			return nil, fmt.Errorf("reading response: %w", err)
-		}
-		return nil, fmt.Errorf("no response from daemon")
-	}
-
-	var resp Response
-	if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil {
-		return nil, fmt.Errorf("parsing response: %w", err)
-	}
-	return &resp, nil
-}
@@ -1,39 +1,167 @@
 package ipc
 
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ProtocolVersion is the version of the request and response format. It is
+// bumped whenever either changes incompatibly; the daemon refuses requests
+// carrying any other version so a CLI and daemon left at different versions
+// by an upgrade fail with a clear message.
+const ProtocolVersion = 2
+
 const (
-	CmdStatus  = "status"
-	CmdUnblock = "unblock"
-	CmdReblock = "reblock"
-	CmdAdd     = "add"
-	CmdRemove  = "remove"
-	CmdList    = "list"
+	CmdHello       = "hello"
+	CmdStatus      = "status"
+	CmdUnblock     = "unblock"
+	CmdReblock     = "reblock"
+	CmdAdd         = "add"
+	CmdRemove      = "remove"
+	CmdList        = "list"
+	CmdFocus       = "focus"
+	CmdReload      = "reload"
+	CmdApplyConfig = "apply_config"
+	CmdGet         = "get"
+	CmdSet         = "set"
+	CmdSubscribe   = "subscribe"
 )
 
+// Request is one line sent to the daemon. Payload holds the command's
+// request struct below; commands without one leave it empty. The ID is
+// echoed in the response and appears in the daemon's log.
 type Request struct {
-	Command string            `json:"command"`
-	Args    map[string]string `json:"args,omitempty"`
+	Version int             `json:"version"`
+	ID      string          `json:"id,omitempty"`
+	Command string          `json:"command"`
+	Payload json.RawMessage `json:"payload,omitempty"`
 }
 
+// Response is the daemon's reply. Data holds the command's data struct
+// below when OK is true; Error is set otherwise.
 type Response struct {
-	OK    bool        `json:"ok"`
-	Data  interface{} `json:"data,omitempty"`
-	Error string      `json:"error,omitempty"`
+	Version int             `json:"version"`
+	ID      string          `json:"id,omitempty"`
+	OK      bool            `json:"ok"`
+	Data    json.RawMessage `json:"data,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// Error codes returned by the daemon.
+const (
+	CodeInvalidRequest   = "invalid_request"
+	CodeUnknownCommand   = "unknown_command"
+	CodeVersionMismatch  = "version_mismatch"
+	CodeInvalidDuration  = "invalid_duration"
+	CodeInvalidDomain    = "invalid_domain"
+	CodeInvalidValue     = "invalid_value"
+	CodeInvalidConfig    = "invalid_config"
+	CodeNotFound         = "not_found"
+	CodePermissionDenied = "permission_denied"
+	CodeLocked           = "locked"
+	CodeCooldown         = "cooldown"
+	CodeBudgetExhausted  = "budget_exhausted"
+	CodeInternal         = "internal"
+
+	// CodeUnauthorized is only used by the HTTP API, for a missing or
+	// wrong token.
+	CodeUnauthorized = "unauthorized"
+)
+
+// Error is a failed request: a stable code for programs and a message for
+// people.
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Errorf builds an Error with the given code.
+func Errorf(code, format string, args ...any) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// HelloData answers CmdHello, which a client may send before its request
+// on the same connection to check it speaks the daemon's protocol.
+type HelloData struct {
+	ProtocolVersion int    `json:"protocol_version"`
+	DaemonVersion   string `json:"daemon_version"`
+}
+
+// Request payloads. Domains may include @group references.
+
+type UnblockRequest struct {
+	Domains  []string `json:"domains,omitempty"`
+	Duration string   `json:"duration"`
+}
+
+type ReblockRequest struct {
+	Domains []string `json:"domains,omitempty"`
+}
+
+type AddRequest struct {
+	Domains []string `json:"domains"`
+	Group   string   `json:"group,omitempty"`
+}
+
+type RemoveRequest struct {
+	Domains []string `json:"domains"`
+}
+
+type ListRequest struct {
+	Expanded bool `json:"expanded,omitempty"`
+}
+
+type FocusRequest struct {
+	Duration string `json:"duration"`
+}
+
+// ApplyConfigRequest carries the full contents of a config file.
+type ApplyConfigRequest struct {
+	Config string `json:"config"`
+}
+
+type GetRequest struct {
+	Key string `json:"key"`
+}
+
+type SetRequest struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
 }
 
 type StatusEntry struct {
-	Domain    string `json:"domain"`
-	State     string `json:"state"`
-	Remaining string `json:"remaining,omitempty"`
+	Domain                 string   `json:"domain"`
+	Groups                 []string `json:"groups,omitempty"`
+	State                  string   `json:"state"`
+	Remaining              string   `json:"remaining,omitempty"`
+	RemainingSeconds       int64    `json:"remaining_seconds"`
+	Schedule               string   `json:"schedule,omitempty"`
+	ScheduleState          string   `json:"schedule_state,omitempty"`
+	NextTransition         string   `json:"next_transition,omitempty"`
+	BudgetRemaining        string   `json:"budget_remaining,omitempty"`
+	BudgetRemainingSeconds *int64   `json:"budget_remaining_seconds,omitempty"`
+	Cooldown               string   `json:"cooldown,omitempty"`
+	CooldownSeconds        int64    `json:"cooldown_seconds"`
 }
 
 type StatusData struct {
-	Uptime  string        `json:"uptime"`
-	Domains []StatusEntry `json:"domains"`
+	Uptime                string        `json:"uptime"`
+	UptimeSeconds         int64         `json:"uptime_seconds"`
+	FocusUntil            string        `json:"focus_until,omitempty"`
+	FocusRemaining        string        `json:"focus_remaining,omitempty"`
+	FocusRemainingSeconds int64         `json:"focus_remaining_seconds"`
+	Domains               []StatusEntry `json:"domains"`
 }
 
 type UnblockData struct {
-	Domains  []string `json:"domains"`
-	Duration string   `json:"duration"`
+	Domains         []string `json:"domains"`
+	Duration        string   `json:"duration"`
+	DurationSeconds int64    `json:"duration_seconds"`
+	BudgetLimited   bool     `json:"budget_limited,omitempty"`
 }
 
 type ReblockData struct {
@@ -46,6 +174,51 @@ type MutateData struct {
 	Domains []string `json:"domains"`
 }
 
+type FocusData struct {
+	Until            string   `json:"until"`
+	Remaining        string   `json:"remaining"`
+	RemainingSeconds int64    `json:"remaining_seconds"`
+	Reblocked        []string `json:"reblocked,omitempty"`
+}
+
 type ListData struct {
-	Domains []string `json:"domains"`
+	Domains  []string            `json:"domains"`
+	Groups   map[string][]string `json:"groups,omitempty"`
+	Expanded map[string][]string `json:"expanded,omitempty"`
+}
+
+type ReloadData struct {
+	Changes []string `json:"changes,omitempty"`
+}
+
+type SettingData struct {
+	Key     string   `json:"key"`
+	Value   string   `json:"value"`
+	Changes []string `json:"changes,omitempty"`
+}
+
+// Event types streamed to subscribers.
+const (
+	EventUnblocked      = "unblocked"
+	EventReblocked      = "reblocked"
+	EventExpiring       = "expiring"
+	EventDomainAdded    = "domain_added"
+	EventDomainRemoved  = "domain_removed"
+	EventTamper         = "tamper"
+	EventConfigReloaded = "config_reloaded"
+	EventConfigRefused  = "config_refused"
+	EventFocusStarted   = "focus_started"
+)
+
+// Event is one line of a subscribe stream. Duration is the unblock length
+// for unblocked, the time left for expiring, and the session length for
+// focus_started.
+type Event struct {
+	Type            string   `json:"type"`
+	Time            string   `json:"time"`
+	Domains         []string `json:"domains,omitempty"`
+	Duration        string   `json:"duration,omitempty"`
+	DurationSeconds int64    `json:"duration_seconds,omitempty"`
+	Reason          string   `json:"reason,omitempty"`
+	Changes         []string `json:"changes,omitempty"`
 }
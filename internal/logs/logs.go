@@ -15,6 +15,24 @@ type Entry struct {
 	Domain    string    `json:"domain"`
 	Duration  string    `json:"duration,omitempty"`
 	Reason    string    `json:"reason,omitempty"`
+	Diff      string    `json:"diff,omitempty"`
+	Changes   []string  `json:"changes,omitempty"`
+	Peer      *Peer     `json:"peer,omitempty"`
+}
+
+// Peer is the local process whose request caused an entry. Entries the
+// daemon makes on its own, such as timer expiries, have none.
+type Peer struct {
+	UID  int    `json:"uid"`
+	PID  int    `json:"pid"`
+	User string `json:"user,omitempty"`
+}
+
+func (p *Peer) String() string {
+	if p.User != "" {
+		return fmt.Sprintf("%s (uid %d, pid %d)", p.User, p.UID, p.PID)
+	}
+	return fmt.Sprintf("uid %d (pid %d)", p.UID, p.PID)
 }
 
 type QueryOpts struct {
@@ -23,10 +41,20 @@ type QueryOpts struct {
 }
 
 type DomainStats struct {
-	Domain       string
-	Unblocks     int
-	TotalTime    time.Duration
-	LastUnblock  time.Time
+	Domain      string
+	Unblocks    int
+	TotalTime   time.Duration
+	LastUnblock time.Time
+}
+
+func (s DomainStats) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Domain       string    `json:"domain"`
+		Unblocks     int       `json:"unblocks"`
+		TotalTime    string    `json:"total_time"`
+		TotalSeconds int64     `json:"total_seconds"`
+		LastUnblock  time.Time `json:"last_unblock"`
+	}{s.Domain, s.Unblocks, s.TotalTime.String(), int64(s.TotalTime.Seconds()), s.LastUnblock})
 }
 
 func Append(path string, entry Entry) error {
@@ -124,6 +152,52 @@ func Stats(entries []Entry) []DomainStats {
 	return result
 }
 
+// DayUsage is the unblocked time a domain actually used on one calendar day.
+type DayUsage struct {
+	Day    string
+	Domain string
+	Used   time.Duration
+}
+
+func (u DayUsage) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Day         string `json:"day"`
+		Domain      string `json:"domain"`
+		Used        string `json:"used"`
+		UsedSeconds int64  `json:"used_seconds"`
+	}{u.Day, u.Domain, u.Used.String(), int64(u.Used.Seconds())})
+}
+
+// DailyUsage sums "usage" events per day and domain, newest day first.
+func DailyUsage(entries []Entry) []DayUsage {
+	type key struct{ day, domain string }
+	totals := make(map[key]time.Duration)
+
+	for _, e := range entries {
+		if e.Event != "usage" {
+			continue
+		}
+		d, err := time.ParseDuration(e.Duration)
+		if err != nil {
+			continue
+		}
+		totals[key{e.Timestamp.Format("2006-01-02"), e.Domain}] += d
+	}
+
+	var result []DayUsage
+	for k, used := range totals {
+		result = append(result, DayUsage{Day: k.day, Domain: k.domain, Used: used})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Day != result[j].Day {
+			return result[i].Day > result[j].Day
+		}
+		return result[i].Domain < result[j].Domain
+	})
+
+	return result
+}
+
 func periodCutoff(period string) time.Time {
 	now := time.Now()
 	switch period {
@@ -0,0 +1,288 @@
+package nft
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"sc/internal/blocker"
+)
+
+// Table is the nftables table sc owns. Nothing outside it is touched.
+const Table = "inet sc"
+
+// Runner executes nft with the given arguments, feeding stdin to it.
+type Runner interface {
+	Run(stdin string, args ...string) ([]byte, error)
+}
+
+// ExecRunner runs the real nft binary.
+type ExecRunner struct{}
+
+func (ExecRunner) Run(stdin string, args ...string) ([]byte, error) {
+	cmd := exec.Command("nft", args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	return cmd.CombinedOutput()
+}
+
+// Resolver returns the current addresses of a host name.
+type Resolver func(host string) ([]net.IP, error)
+
+type cached struct {
+	addrs    []netip.Addr
+	resolved time.Time
+}
+
+// Options configures an nftables Blocker.
+type Options struct {
+	// Ranges maps a domain to CIDRs or addresses blocked along with it.
+	Ranges map[string][]string
+	// Refresh is how long resolved addresses are reused before re-resolving.
+	Refresh time.Duration
+	// Exclude lists addresses that must never be dropped, such as the
+	// upstream DNS servers the daemon itself depends on.
+	Exclude []string
+}
+
+// Blocker drops traffic to the resolved addresses of blocked hosts, plus any
+// configured IP ranges, using a dedicated nftables table.
+type Blocker struct {
+	runner  Runner
+	resolve Resolver
+	ranges  map[string][]netip.Prefix
+	refresh time.Duration
+	exclude map[netip.Addr]bool
+
+	mu      sync.Mutex
+	cache   map[string]cached
+	applied string
+}
+
+func New(runner Runner, resolve Resolver, opts Options) (*Blocker, error) {
+	parsed := make(map[string][]netip.Prefix, len(opts.Ranges))
+	for domain, cidrs := range opts.Ranges {
+		for _, c := range cidrs {
+			p, err := parsePrefix(c)
+			if err != nil {
+				return nil, fmt.Errorf("ip range %q for %s: %w", c, domain, err)
+			}
+			parsed[domain] = append(parsed[domain], p)
+		}
+	}
+
+	exclude := make(map[netip.Addr]bool, len(opts.Exclude))
+	for _, e := range opts.Exclude {
+		addr, err := netip.ParseAddr(e)
+		if err != nil {
+			return nil, fmt.Errorf("exclude address %q: %w", e, err)
+		}
+		exclude[addr.Unmap()] = true
+	}
+
+	return &Blocker{
+		runner:  runner,
+		resolve: resolve,
+		ranges:  parsed,
+		refresh: opts.Refresh,
+		exclude: exclude,
+		cache:   make(map[string]cached),
+	}, nil
+}
+
+func (b *Blocker) Name() string { return "nftables" }
+
+func (b *Blocker) Apply(entries []blocker.Entry) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.resolveStale(blocker.Names(entries), time.Now())
+
+	var prefixes []netip.Prefix
+	for _, e := range entries {
+		prefixes = append(prefixes, b.ranges[e.Domain]...)
+		for _, host := range e.Hosts {
+			for _, addr := range b.cache[host].addrs {
+				if b.exclude[addr] {
+					continue
+				}
+				prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			}
+		}
+	}
+
+	script := Ruleset(prefixes)
+	if script == b.applied {
+		return false, nil
+	}
+	if out, err := b.runner.Run(script, "-f", "-"); err != nil {
+		return false, fmt.Errorf("nft -f: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	b.applied = script
+	return true, nil
+}
+
+func (b *Blocker) Remove() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.applied = ""
+	return Remove(b.runner)
+}
+
+func (b *Blocker) Verify(entries []blocker.Entry) error {
+	out, err := b.runner.Run("", tableCmd("list")...)
+	if err != nil {
+		return fmt.Errorf("nftables table %s is missing: %s", Table, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// Remove deletes the sc table if it exists.
+func Remove(runner Runner) error {
+	out, err := runner.Run("", tableCmd("delete")...)
+	if err != nil && !strings.Contains(string(out), "No such file") {
+		return fmt.Errorf("nft delete table: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func tableCmd(verb string) []string {
+	return append([]string{verb, "table"}, strings.Fields(Table)...)
+}
+
+// resolveStale refreshes cached addresses for hosts never resolved or older
+// than the refresh interval. Lookups run concurrently; a failed lookup keeps
+// the previous answer.
+func (b *Blocker) resolveStale(hosts []string, now time.Time) {
+	// Pick the hosts first: the lookups below write to b.cache.
+	var stale []string
+	for _, host := range hosts {
+		if c, ok := b.cache[host]; ok && now.Sub(c.resolved) < b.refresh {
+			continue
+		}
+		stale = append(stale, host)
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, host := range stale {
+		wg.Add(1)
+		go func(host string) {
+			defer wg.Done()
+			ips, err := b.resolve(host)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				c := b.cache[host]
+				c.resolved = now
+				b.cache[host] = c
+				return
+			}
+			b.cache[host] = cached{addrs: usableAddrs(ips), resolved: now}
+		}(host)
+	}
+	wg.Wait()
+}
+
+// Ruleset renders the complete sc table for the given destinations. Loading
+// it with "nft -f" atomically replaces any previous version of the table.
+func Ruleset(prefixes []netip.Prefix) string {
+	var v4, v6 []string
+	seen := make(map[netip.Prefix]bool)
+	for _, p := range normalize(prefixes) {
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		if p.Addr().Is4() {
+			v4 = append(v4, formatPrefix(p))
+		} else {
+			v6 = append(v6, formatPrefix(p))
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "table %s\n", Table)
+	fmt.Fprintf(&sb, "delete table %s\n", Table)
+	fmt.Fprintf(&sb, "table %s {\n", Table)
+	writeSet(&sb, "blocked_v4", "ipv4_addr", v4)
+	writeSet(&sb, "blocked_v6", "ipv6_addr", v6)
+	for _, hook := range []string{"output", "forward"} {
+		fmt.Fprintf(&sb, "\tchain %s {\n", hook)
+		fmt.Fprintf(&sb, "\t\ttype filter hook %s priority filter; policy accept;\n", hook)
+		sb.WriteString("\t\tip daddr @blocked_v4 counter drop\n")
+		sb.WriteString("\t\tip6 daddr @blocked_v6 counter drop\n")
+		sb.WriteString("\t}\n")
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+func writeSet(sb *strings.Builder, name, typ string, elems []string) {
+	fmt.Fprintf(sb, "\tset %s {\n", name)
+	fmt.Fprintf(sb, "\t\ttype %s\n", typ)
+	sb.WriteString("\t\tflags interval\n")
+	sb.WriteString("\t\tauto-merge\n")
+	if len(elems) > 0 {
+		fmt.Fprintf(sb, "\t\telements = { %s }\n", strings.Join(elems, ", "))
+	}
+	sb.WriteString("\t}\n")
+}
+
+// normalize masks prefixes to their network address and sorts them so the
+// rendered ruleset is stable across ticks.
+func normalize(prefixes []netip.Prefix) []netip.Prefix {
+	result := make([]netip.Prefix, 0, len(prefixes))
+	for _, p := range prefixes {
+		result = append(result, p.Masked())
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if c := result[i].Addr().Compare(result[j].Addr()); c != 0 {
+			return c < 0
+		}
+		return result[i].Bits() < result[j].Bits()
+	})
+	return result
+}
+
+func formatPrefix(p netip.Prefix) string {
+	if p.IsSingleIP() {
+		return p.Addr().String()
+	}
+	return p.String()
+}
+
+func parsePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		return netip.ParsePrefix(s)
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// usableAddrs drops answers that would block nothing useful, such as the
+// unspecified or loopback addresses a blocking resolver hands out.
+func usableAddrs(ips []net.IP) []netip.Addr {
+	var addrs []netip.Addr
+	for _, ip := range ips {
+		addr, ok := netip.AddrFromSlice(ip)
+		if !ok {
+			continue
+		}
+		addr = addr.Unmap()
+		if addr.IsUnspecified() || addr.IsLoopback() {
+			continue
+		}
+		addrs = append(addrs, addr)
+	}
+	return addrs
+}
@@ -0,0 +1,315 @@
+package nft
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"sc/internal/blocker"
+)
+
+// elements returns the element list of the named set in script, or "" if
+// the set is empty.
+func elements(t *testing.T, script, set string) string {
+	t.Helper()
+
+	start := strings.Index(script, "set "+set+" {")
+	if start == -1 {
+		t.Fatalf("set %s missing from ruleset:\n%s", set, script)
+	}
+	body := script[start:]
+	body = body[:strings.Index(body, "\t}\n")]
+
+	_, elems, ok := strings.Cut(body, "elements = { ")
+	if !ok {
+		return ""
+	}
+	return strings.TrimSuffix(strings.TrimSpace(elems), " }")
+}
+
+func prefixes(t *testing.T, cidrs ...string) []netip.Prefix {
+	t.Helper()
+
+	var result []netip.Prefix
+	for _, c := range cidrs {
+		p, err := parsePrefix(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result = append(result, p)
+	}
+	return result
+}
+
+func TestRuleset(t *testing.T) {
+	tests := []struct {
+		name   string
+		in     []string
+		v4, v6 string
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name: "single addresses",
+			in:   []string{"192.0.2.1", "2001:db8::1"},
+			v4:   "192.0.2.1",
+			v6:   "2001:db8::1",
+		},
+		{
+			name: "duplicates",
+			in:   []string{"192.0.2.1", "192.0.2.1/32", "2001:db8::1", "2001:db8::1/128"},
+			v4:   "192.0.2.1",
+			v6:   "2001:db8::1",
+		},
+		{
+			name: "masked to network",
+			in:   []string{"198.51.100.77/24", "2001:db8:1:2::5/64"},
+			v4:   "198.51.100.0/24",
+			v6:   "2001:db8:1:2::/64",
+		},
+		{
+			name: "duplicates after masking",
+			in:   []string{"198.51.100.7/24", "198.51.100.0/24", "198.51.100.200/24"},
+			v4:   "198.51.100.0/24",
+		},
+		{
+			name: "sorted",
+			in:   []string{"203.0.113.9", "192.0.2.1", "198.51.100.0/24", "2001:db8::2", "2001:db8::1"},
+			v4:   "192.0.2.1, 198.51.100.0/24, 203.0.113.9",
+			v6:   "2001:db8::1, 2001:db8::2",
+		},
+		{
+			name: "only v6",
+			in:   []string{"2001:db8::/32"},
+			v6:   "2001:db8::/32",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script := Ruleset(prefixes(t, tt.in...))
+
+			if !strings.HasPrefix(script, "table inet sc\ndelete table inet sc\ntable inet sc {\n") {
+				t.Errorf("ruleset does not replace the table atomically:\n%s", script)
+			}
+			if got := elements(t, script, "blocked_v4"); got != tt.v4 {
+				t.Errorf("blocked_v4 = %q, want %q", got, tt.v4)
+			}
+			if got := elements(t, script, "blocked_v6"); got != tt.v6 {
+				t.Errorf("blocked_v6 = %q, want %q", got, tt.v6)
+			}
+			for _, hook := range []string{"output", "forward"} {
+				if !strings.Contains(script, "type filter hook "+hook) {
+					t.Errorf("ruleset has no %s chain", hook)
+				}
+			}
+		})
+	}
+}
+
+func TestRulesetStable(t *testing.T) {
+	a := Ruleset(prefixes(t, "192.0.2.1", "2001:db8::1", "198.51.100.0/24"))
+	b := Ruleset(prefixes(t, "198.51.100.0/24", "2001:db8::1", "192.0.2.1"))
+	if a != b {
+		t.Errorf("ruleset depends on input order:\n%s\nvs\n%s", a, b)
+	}
+}
+
+// fakeRunner records nft invocations instead of running them.
+type fakeRunner struct {
+	scripts []string
+	err     error
+}
+
+func (r *fakeRunner) Run(stdin string, args ...string) ([]byte, error) {
+	if r.err != nil {
+		return []byte("nft failed"), r.err
+	}
+	r.scripts = append(r.scripts, stdin)
+	return nil, nil
+}
+
+// fakeResolver answers from a fixed table and counts lookups per host.
+type fakeResolver struct {
+	mu      sync.Mutex
+	answers map[string][]string
+	fail    bool
+	lookups map[string]int
+}
+
+func (r *fakeResolver) resolve(host string) ([]net.IP, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.lookups == nil {
+		r.lookups = make(map[string]int)
+	}
+	r.lookups[host]++
+	if r.fail {
+		return nil, errors.New("lookup failed")
+	}
+	var ips []net.IP
+	for _, a := range r.answers[host] {
+		ips = append(ips, net.ParseIP(a))
+	}
+	return ips, nil
+}
+
+func newBlocker(t *testing.T, runner Runner, resolver *fakeResolver, opts Options) *Blocker {
+	t.Helper()
+
+	b, err := New(runner, resolver.resolve, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+var exampleEntries = []blocker.Entry{
+	{Domain: "example.com", Hosts: []string{"example.com", "www.example.com"}},
+}
+
+func TestApplyExcludesUpstreams(t *testing.T) {
+	runner := &fakeRunner{}
+	resolver := &fakeResolver{answers: map[string][]string{
+		// A CDN can share an address with the resolver the daemon uses;
+		// blocking it would cut the daemon off from DNS.
+		"example.com":     {"192.0.2.1", "9.9.9.9", "0.0.0.0", "::"},
+		"www.example.com": {"192.0.2.2", "2001:db8::1", "::1", "127.0.0.1"},
+	}}
+	b := newBlocker(t, runner, resolver, Options{
+		Ranges:  map[string][]string{"example.com": {"203.0.113.0/24"}},
+		Refresh: time.Hour,
+		Exclude: []string{"9.9.9.9"},
+	})
+
+	changed, err := b.Apply(exampleEntries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed || len(runner.scripts) != 1 {
+		t.Fatalf("Apply = %v with %d nft calls, want a single call", changed, len(runner.scripts))
+	}
+
+	script := runner.scripts[0]
+	if got, want := elements(t, script, "blocked_v4"), "192.0.2.1, 192.0.2.2, 203.0.113.0/24"; got != want {
+		t.Errorf("blocked_v4 = %q, want %q", got, want)
+	}
+	if got, want := elements(t, script, "blocked_v6"), "2001:db8::1"; got != want {
+		t.Errorf("blocked_v6 = %q, want %q", got, want)
+	}
+}
+
+func TestApplyUnchanged(t *testing.T) {
+	runner := &fakeRunner{}
+	resolver := &fakeResolver{answers: map[string][]string{
+		"example.com": {"192.0.2.1"},
+		"other.com":   {"192.0.2.9"},
+	}}
+	b := newBlocker(t, runner, resolver, Options{Refresh: time.Hour})
+
+	if _, err := b.Apply(exampleEntries); err != nil {
+		t.Fatal(err)
+	}
+	changed, err := b.Apply(exampleEntries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed || len(runner.scripts) != 1 {
+		t.Errorf("second Apply = %v with %d nft calls, want no change and no call", changed, len(runner.scripts))
+	}
+
+	more := append(exampleEntries, blocker.Entry{Domain: "other.com", Hosts: []string{"other.com"}})
+	changed, err = b.Apply(more)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed || len(runner.scripts) != 2 {
+		t.Errorf("Apply with a new domain = %v with %d nft calls, want a second call", changed, len(runner.scripts))
+	}
+}
+
+func TestApplyRefresh(t *testing.T) {
+	tests := []struct {
+		name    string
+		refresh time.Duration
+		lookups int
+	}{
+		{"cached", time.Hour, 1},
+		{"expired", 0, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver := &fakeResolver{answers: map[string][]string{"example.com": {"192.0.2.1"}}}
+			b := newBlocker(t, &fakeRunner{}, resolver, Options{Refresh: tt.refresh})
+
+			for range 3 {
+				if _, err := b.Apply(exampleEntries); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for _, host := range exampleEntries[0].Hosts {
+				if got := resolver.lookups[host]; got != tt.lookups {
+					t.Errorf("%s resolved %d times, want %d", host, got, tt.lookups)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyKeepsAddressesOnLookupFailure(t *testing.T) {
+	runner := &fakeRunner{}
+	resolver := &fakeResolver{answers: map[string][]string{"example.com": {"192.0.2.1"}}}
+	b := newBlocker(t, runner, resolver, Options{})
+
+	if _, err := b.Apply(exampleEntries); err != nil {
+		t.Fatal(err)
+	}
+	resolver.fail = true
+	changed, err := b.Apply(exampleEntries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed {
+		t.Errorf("Apply after a failed lookup changed the ruleset:\n%s", runner.scripts[len(runner.scripts)-1])
+	}
+}
+
+func TestApplyRetriesAfterNFTError(t *testing.T) {
+	runner := &fakeRunner{err: errors.New("exit status 1")}
+	resolver := &fakeResolver{answers: map[string][]string{"example.com": {"192.0.2.1"}}}
+	b := newBlocker(t, runner, resolver, Options{Refresh: time.Hour})
+
+	if _, err := b.Apply(exampleEntries); err == nil || !strings.Contains(err.Error(), "nft failed") {
+		t.Fatalf("Apply = %v, want the nft error with its output", err)
+	}
+
+	runner.err = nil
+	changed, err := b.Apply(exampleEntries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed || len(runner.scripts) != 1 {
+		t.Errorf("Apply after a failure = %v with %d nft calls, want the ruleset loaded", changed, len(runner.scripts))
+	}
+}
+
+func TestNewRejectsBadOptions(t *testing.T) {
+	tests := map[string]Options{
+		"bad range":   {Ranges: map[string][]string{"example.com": {"203.0.113.0/33"}}},
+		"bad exclude": {Exclude: []string{"not-an-ip"}},
+	}
+	for name, opts := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := New(&fakeRunner{}, (&fakeResolver{}).resolve, opts); err == nil {
+				t.Error("New accepted invalid options")
+			}
+		})
+	}
+}
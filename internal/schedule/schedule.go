@@ -0,0 +1,220 @@
+package schedule
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Window is a recurring period during which a schedule's domains are allowed.
+// An empty Days list means every day; empty From/To mean start/end of day.
+// A To earlier than From wraps past midnight.
+type Window struct {
+	Days []string `yaml:"days,omitempty"`
+	From string   `yaml:"from,omitempty"`
+	To   string   `yaml:"to,omitempty"`
+}
+
+type Schedule struct {
+	Name    string   `yaml:"name"`
+	Domains []string `yaml:"domains"`
+	Allow   []Window `yaml:"allow"`
+}
+
+// Status describes how schedules currently govern a domain. Next is zero
+// when the domain's state does not change within the coming week.
+type Status struct {
+	Schedule string
+	Allowed  bool
+	Next     time.Time
+}
+
+const dayMinutes = 24 * 60
+
+var dayNames = map[string][]time.Weekday{
+	"sun":       {time.Sunday},
+	"mon":       {time.Monday},
+	"tue":       {time.Tuesday},
+	"wed":       {time.Wednesday},
+	"thu":       {time.Thursday},
+	"fri":       {time.Friday},
+	"sat":       {time.Saturday},
+	"weekdays":  {time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+	"weekends":  {time.Saturday, time.Sunday},
+	"daily":     {time.Sunday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday},
+	"sunday":    {time.Sunday},
+	"monday":    {time.Monday},
+	"tuesday":   {time.Tuesday},
+	"wednesday": {time.Wednesday},
+	"thursday":  {time.Thursday},
+	"friday":    {time.Friday},
+	"saturday":  {time.Saturday},
+}
+
+func (s Schedule) Validate() error {
+	if s.Name == "" {
+		return fmt.Errorf("name required")
+	}
+	if len(s.Allow) == 0 {
+		return fmt.Errorf("at least one allow window required")
+	}
+	for i, w := range s.Allow {
+		if _, _, _, err := w.parse(); err != nil {
+			return fmt.Errorf("allow[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func (s Schedule) Covers(domain string) bool {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	for _, d := range s.Domains {
+		if strings.ToLower(strings.TrimSpace(d)) == domain {
+			return true
+		}
+	}
+	return false
+}
+
+// Evaluate reports whether domain is currently allowed by any schedule and
+// when that next changes. The second result is false if no schedule covers
+// the domain.
+func Evaluate(schedules []Schedule, domain string, now time.Time) (Status, bool) {
+	var covering []Schedule
+	for _, s := range schedules {
+		if s.Covers(domain) {
+			covering = append(covering, s)
+		}
+	}
+	if len(covering) == 0 {
+		return Status{}, false
+	}
+
+	horizon := Midnight(now).AddDate(0, 0, 8)
+	for _, iv := range merge(expand(covering, now)) {
+		if now.Before(iv.start) {
+			return Status{Schedule: iv.name, Next: iv.start}, true
+		}
+		if now.Before(iv.end) {
+			st := Status{Schedule: iv.name, Allowed: true}
+			if iv.end.Before(horizon) {
+				st.Next = iv.end
+			}
+			return st, true
+		}
+	}
+
+	return Status{Schedule: covering[0].Name}, true
+}
+
+type interval struct {
+	start, end time.Time
+	name       string
+}
+
+// expand turns each window into concrete intervals from yesterday (for
+// windows wrapping past midnight) through the next week.
+func expand(schedules []Schedule, now time.Time) []interval {
+	today := Midnight(now)
+
+	var result []interval
+	for _, s := range schedules {
+		for _, w := range s.Allow {
+			days, from, to, err := w.parse()
+			if err != nil {
+				continue
+			}
+			if to <= from {
+				to += dayMinutes
+			}
+			for off := -1; off <= 8; off++ {
+				day := today.AddDate(0, 0, off)
+				if !days[day.Weekday()] {
+					continue
+				}
+				result = append(result, interval{
+					start: clock(day, from),
+					end:   clock(day, to),
+					name:  s.Name,
+				})
+			}
+		}
+	}
+	return result
+}
+
+func merge(ivs []interval) []interval {
+	sort.Slice(ivs, func(i, j int) bool { return ivs[i].start.Before(ivs[j].start) })
+
+	var merged []interval
+	for _, iv := range ivs {
+		if n := len(merged); n > 0 && !iv.start.After(merged[n-1].end) {
+			if iv.end.After(merged[n-1].end) {
+				merged[n-1].end = iv.end
+			}
+			continue
+		}
+		merged = append(merged, iv)
+	}
+	return merged
+}
+
+func (w Window) parse() (days [7]bool, from, to int, err error) {
+	if len(w.Days) == 0 {
+		for i := range days {
+			days[i] = true
+		}
+	}
+	for _, name := range w.Days {
+		wds, ok := dayNames[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return days, 0, 0, fmt.Errorf("unknown day %q", name)
+		}
+		for _, wd := range wds {
+			days[wd] = true
+		}
+	}
+
+	from, to = 0, dayMinutes
+	if w.From != "" {
+		if from, err = parseClock(w.From); err != nil {
+			return days, 0, 0, fmt.Errorf("from: %w", err)
+		}
+	}
+	if w.To != "" {
+		if to, err = parseClock(w.To); err != nil {
+			return days, 0, 0, fmt.Errorf("to: %w", err)
+		}
+	}
+	if from == to {
+		return days, 0, 0, fmt.Errorf("from and to are equal (%s)", w.From)
+	}
+	return days, from, to, nil
+}
+
+// parseClock parses "HH:MM" into minutes after midnight. "24:00" is allowed
+// as an end-of-day marker.
+func parseClock(s string) (int, error) {
+	hh, mm, ok := strings.Cut(strings.TrimSpace(s), ":")
+	if !ok {
+		return 0, fmt.Errorf("invalid time %q, want HH:MM", s)
+	}
+	h, err1 := strconv.Atoi(hh)
+	m, err2 := strconv.Atoi(mm)
+	if err1 != nil || err2 != nil || h < 0 || m < 0 || m > 59 || h > 24 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("invalid time %q, want HH:MM", s)
+	}
+	return h*60 + m, nil
+}
+
+// Midnight returns the start of t's day in t's location.
+func Midnight(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+func clock(day time.Time, minutes int) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), 0, minutes, 0, 0, day.Location())
+}
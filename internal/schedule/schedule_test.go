@@ -0,0 +1,263 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+// at parses "2006-01-02 15:04" in loc.
+func at(t *testing.T, loc *time.Location, s string) time.Time {
+	t.Helper()
+
+	ts, err := time.ParseInLocation("2006-01-02 15:04", s, loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ts
+}
+
+func sched(name string, allow ...Window) Schedule {
+	return Schedule{Name: name, Domains: []string{"example.com"}, Allow: allow}
+}
+
+func TestEvaluate(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 2026-10-19 is a Monday.
+	tests := []struct {
+		name      string
+		schedules []Schedule
+		loc       *time.Location
+		now       string
+		allowed   bool
+		next      string // "" when nothing changes within the horizon
+		schedule  string
+	}{
+		{
+			name:      "inside a daytime window",
+			schedules: []Schedule{sched("work", Window{Days: []string{"weekdays"}, From: "09:00", To: "17:00"})},
+			now:       "2026-10-19 12:00",
+			allowed:   true,
+			next:      "2026-10-19 17:00",
+			schedule:  "work",
+		},
+		{
+			name:      "before a daytime window",
+			schedules: []Schedule{sched("work", Window{Days: []string{"weekdays"}, From: "09:00", To: "17:00"})},
+			now:       "2026-10-19 08:00",
+			next:      "2026-10-19 09:00",
+			schedule:  "work",
+		},
+		{
+			name:      "weekend skipped",
+			schedules: []Schedule{sched("work", Window{Days: []string{"weekdays"}, From: "09:00", To: "17:00"})},
+			now:       "2026-10-23 18:00",
+			next:      "2026-10-26 09:00",
+			schedule:  "work",
+		},
+		{
+			name:      "window opening before midnight",
+			schedules: []Schedule{sched("night", Window{From: "22:00", To: "06:00"})},
+			now:       "2026-10-19 23:00",
+			allowed:   true,
+			next:      "2026-10-20 06:00",
+			schedule:  "night",
+		},
+		{
+			name:      "window carried over from yesterday",
+			schedules: []Schedule{sched("night", Window{Days: []string{"sun"}, From: "22:00", To: "02:00"})},
+			now:       "2026-10-19 01:00",
+			allowed:   true,
+			next:      "2026-10-19 02:00",
+			schedule:  "night",
+		},
+		{
+			name:      "after an overnight window",
+			schedules: []Schedule{sched("night", Window{Days: []string{"sun"}, From: "22:00", To: "02:00"})},
+			now:       "2026-10-19 02:00",
+			next:      "2026-10-25 22:00",
+			schedule:  "night",
+		},
+		{
+			name: "adjacent windows merge",
+			schedules: []Schedule{sched("split",
+				Window{From: "09:00", To: "12:00"},
+				Window{From: "12:00", To: "15:00"},
+			)},
+			now:      "2026-10-19 11:00",
+			allowed:  true,
+			next:     "2026-10-19 15:00",
+			schedule: "split",
+		},
+		{
+			name: "overlapping schedules merge",
+			schedules: []Schedule{
+				sched("morning", Window{From: "09:00", To: "12:00"}),
+				sched("midday", Window{From: "11:00", To: "14:00"}),
+			},
+			now:      "2026-10-19 10:00",
+			allowed:  true,
+			next:     "2026-10-19 14:00",
+			schedule: "morning",
+		},
+		{
+			name: "overnight window runs into an all-day one",
+			schedules: []Schedule{sched("weekend",
+				Window{Days: []string{"fri"}, From: "20:00", To: "24:00"},
+				Window{Days: []string{"weekends"}},
+			)},
+			now:      "2026-10-23 21:00",
+			allowed:  true,
+			next:     "2026-10-26 00:00",
+			schedule: "weekend",
+		},
+		{
+			name:      "next window a week away",
+			schedules: []Schedule{sched("weekly", Window{Days: []string{"mon"}, From: "09:00", To: "10:00"})},
+			now:       "2026-10-19 10:30",
+			next:      "2026-10-26 09:00",
+			schedule:  "weekly",
+		},
+		{
+			name:      "always allowed has no transition within the horizon",
+			schedules: []Schedule{sched("always", Window{Days: []string{"daily"}})},
+			now:       "2026-10-19 12:00",
+			allowed:   true,
+			schedule:  "always",
+		},
+		{
+			name: "allowed until the horizon edge",
+			schedules: []Schedule{sched("all-week",
+				Window{Days: []string{"weekdays"}},
+				Window{Days: []string{"weekends"}, From: "00:00", To: "24:00"},
+			)},
+			now:      "2026-10-19 00:00",
+			allowed:  true,
+			schedule: "all-week",
+		},
+		{
+			name:      "overnight window across spring forward",
+			schedules: []Schedule{sched("night", Window{From: "22:00", To: "06:00"})},
+			loc:       ny,
+			now:       "2026-03-08 05:00",
+			allowed:   true,
+			next:      "2026-03-08 06:00",
+			schedule:  "night",
+		},
+		{
+			name:      "overnight window opened before spring forward",
+			schedules: []Schedule{sched("night", Window{From: "22:00", To: "06:00"})},
+			loc:       ny,
+			now:       "2026-03-07 23:00",
+			allowed:   true,
+			next:      "2026-03-08 06:00",
+			schedule:  "night",
+		},
+		{
+			name:      "window after fall back",
+			schedules: []Schedule{sched("sunday", Window{Days: []string{"sun"}, From: "09:00", To: "10:00"})},
+			loc:       ny,
+			now:       "2026-10-31 12:00",
+			next:      "2026-11-01 09:00",
+			schedule:  "sunday",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc := tt.loc
+			if loc == nil {
+				loc = time.UTC
+			}
+
+			st, ok := Evaluate(tt.schedules, "example.com", at(t, loc, tt.now))
+			if !ok {
+				t.Fatal("Evaluate reported example.com as not covered")
+			}
+			if st.Allowed != tt.allowed {
+				t.Errorf("Allowed = %v, want %v", st.Allowed, tt.allowed)
+			}
+			if st.Schedule != tt.schedule {
+				t.Errorf("Schedule = %q, want %q", st.Schedule, tt.schedule)
+			}
+
+			var next time.Time
+			if tt.next != "" {
+				next = at(t, loc, tt.next)
+			}
+			if !st.Next.Equal(next) {
+				t.Errorf("Next = %v, want %v", st.Next, next)
+			}
+		})
+	}
+}
+
+// The wall clock skips or repeats an hour on DST changeover days, so an
+// overnight window lasts an hour less or more in real time.
+func TestEvaluateDSTDuration(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	schedules := []Schedule{sched("night", Window{From: "22:00", To: "06:00"})}
+
+	tests := []struct {
+		start string
+		want  time.Duration
+	}{
+		{"2026-03-07 22:00", 7 * time.Hour},
+		{"2026-10-31 22:00", 9 * time.Hour},
+		{"2026-10-19 22:00", 8 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.start, func(t *testing.T) {
+			start := at(t, ny, tt.start)
+			st, _ := Evaluate(schedules, "example.com", start)
+			if !st.Allowed {
+				t.Fatal("window not open at its start")
+			}
+			if got := st.Next.Sub(start); got != tt.want {
+				t.Errorf("window lasts %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateNotCovered(t *testing.T) {
+	schedules := []Schedule{{Name: "other", Domains: []string{"other.com"}, Allow: []Window{{}}}}
+	if _, ok := Evaluate(schedules, "example.com", time.Now()); ok {
+		t.Error("Evaluate reported an uncovered domain as covered")
+	}
+	if _, ok := Evaluate(schedules, " Other.COM ", time.Now()); !ok {
+		t.Error("Evaluate did not match a domain case-insensitively")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		window  Window
+		wantErr bool
+	}{
+		{"all day", Window{}, false},
+		{"end of day", Window{From: "18:00", To: "24:00"}, false},
+		{"overnight", Window{From: "22:00", To: "06:00"}, false},
+		{"unknown day", Window{Days: []string{"funday"}}, true},
+		{"bad time", Window{From: "9am"}, true},
+		{"minutes out of range", Window{From: "09:60"}, true},
+		{"past end of day", Window{To: "24:01"}, true},
+		{"empty window", Window{From: "09:00", To: "09:00"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := sched("s", tt.window).Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
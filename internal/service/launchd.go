@@ -0,0 +1,75 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"text/template"
+)
+
+const launchdPath = "/Library/LaunchDaemons/com.sc.daemon.plist"
+
+const plistTemplate = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN"
+  "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+    <key>Label</key>
+    <string>com.sc.daemon</string>
+    <key>ProgramArguments</key>
+    <array>
+        <string>{{.BinaryPath}}</string>
+        <string>daemon</string>
+    </array>
+    <key>RunAtLoad</key>
+    <true/>
+    <key>KeepAlive</key>
+    <true/>
+    <key>StandardOutPath</key>
+    <string>{{.LogPath}}</string>
+    <key>StandardErrorPath</key>
+    <string>{{.LogPath}}</string>
+</dict>
+</plist>
+`
+
+type launchd struct {
+	path string
+}
+
+func (l *launchd) Name() string { return "launchd" }
+func (l *launchd) Path() string { return l.path }
+
+func (l *launchd) Install(opts Options) error {
+	if err := writeTemplate(l.path, plistTemplate, opts); err != nil {
+		return err
+	}
+	if err := exec.Command("launchctl", "load", l.path).Run(); err != nil {
+		return fmt.Errorf("launchctl load failed: %w", err)
+	}
+	return nil
+}
+
+func (l *launchd) Uninstall() error {
+	_ = exec.Command("launchctl", "unload", l.path).Run()
+
+	if err := os.Remove(l.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove plist: %w", err)
+	}
+	return nil
+}
+
+func writeTemplate(path, text string, opts Options) error {
+	tmpl, err := template.New("service").Parse(text)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create %s: %w", path, err)
+	}
+	defer f.Close()
+
+	return tmpl.Execute(f, opts)
+}
@@ -0,0 +1,34 @@
+package service
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// Options describes the daemon process a service manager should supervise.
+type Options struct {
+	BinaryPath string
+	LogPath    string
+	ConfigDir  string
+	DataDir    string
+}
+
+// Manager installs and removes the sc daemon with the host's init system.
+type Manager interface {
+	Name() string
+	Path() string
+	Install(opts Options) error
+	Uninstall() error
+}
+
+// New returns the service manager for the current OS.
+func New() (Manager, error) {
+	switch runtime.GOOS {
+	case "darwin":
+		return &launchd{path: launchdPath}, nil
+	case "linux":
+		return &systemd{path: systemdPath}, nil
+	default:
+		return nil, fmt.Errorf("unsupported OS for service install: %s", runtime.GOOS)
+	}
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+const (
+	systemdPath = "/etc/systemd/system/sc.service"
+	systemdUnit = "sc.service"
+)
+
+const unitTemplate = `[Unit]
+Description=sc website blocker daemon
+After=network.target
+
+[Service]
+Type=simple
+ExecStart={{.BinaryPath}} daemon
+Restart=always
+RestartSec=2
+StandardOutput=append:{{.LogPath}}
+StandardError=append:{{.LogPath}}
+
+# The daemon only needs to write /etc/hosts and its own config/data dirs.
+NoNewPrivileges=true
+ProtectSystem=true
+ProtectHome=true
+PrivateTmp=true
+ProtectKernelTunables=true
+ProtectKernelModules=true
+ProtectControlGroups=true
+RestrictRealtime=true
+RestrictSUIDSGID=true
+LockPersonality=true
+RestrictAddressFamilies=AF_UNIX AF_INET AF_INET6 AF_NETLINK
+ReadWritePaths={{.ConfigDir}} {{.DataDir}}
+
+[Install]
+WantedBy=multi-user.target
+`
+
+type systemd struct {
+	path string
+}
+
+func (s *systemd) Name() string { return "systemd" }
+func (s *systemd) Path() string { return s.path }
+
+func (s *systemd) Install(opts Options) error {
+	if err := writeTemplate(s.path, unitTemplate, opts); err != nil {
+		return err
+	}
+	if err := systemctl("daemon-reload"); err != nil {
+		return err
+	}
+	return systemctl("enable", "--now", systemdUnit)
+}
+
+func (s *systemd) Uninstall() error {
+	_ = systemctl("disable", "--now", systemdUnit)
+
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove unit: %w", err)
+	}
+	return systemctl("daemon-reload")
+}
+
+func systemctl(args ...string) error {
+	out, err := exec.Command("systemctl", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("systemctl %s failed: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+//go:build linux
+
+package watch
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const inotifyMask = unix.IN_CLOSE_WRITE | unix.IN_MOVED_TO | unix.IN_MOVED_FROM |
+	unix.IN_DELETE | unix.IN_ATTRIB
+
+type inotify struct {
+	file *os.File
+}
+
+func start(w *Watcher) (impl, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("inotify init: %w", err)
+	}
+
+	dirs := make(map[int]string)
+	for _, dir := range w.dirs() {
+		wd, err := unix.InotifyAddWatch(fd, dir, inotifyMask)
+		if err != nil {
+			unix.Close(fd)
+			return nil, fmt.Errorf("inotify watch %s: %w", dir, err)
+		}
+		dirs[wd] = dir
+	}
+
+	// A non-blocking fd is registered with the runtime poller, so Close
+	// unblocks the pending Read below.
+	in := &inotify{file: os.NewFile(uintptr(fd), "inotify")}
+	go in.read(w, dirs)
+	return in, nil
+}
+
+func (in *inotify) read(w *Watcher, dirs map[int]string) {
+	buf := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+	for {
+		n, err := in.file.Read(buf)
+		if err != nil {
+			return
+		}
+
+		for off := 0; off+unix.SizeofInotifyEvent <= n; {
+			ev := (*unix.InotifyEvent)(unsafe.Pointer(&buf[off]))
+			nameStart := off + unix.SizeofInotifyEvent
+			nameEnd := nameStart + int(ev.Len)
+			off = nameEnd
+			if nameEnd > n {
+				break
+			}
+
+			dir, ok := dirs[int(ev.Wd)]
+			if !ok || ev.Len == 0 {
+				continue
+			}
+			name := string(bytes.TrimRight(buf[nameStart:nameEnd], "\x00"))
+			w.send(filepath.Join(dir, name))
+		}
+	}
+}
+
+func (in *inotify) close() error {
+	return in.file.Close()
+}
@@ -0,0 +1,137 @@
+//go:build darwin
+
+package watch
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const kqueueFlags = unix.NOTE_WRITE | unix.NOTE_DELETE | unix.NOTE_RENAME |
+	unix.NOTE_EXTEND | unix.NOTE_ATTRIB
+
+// kqueue watches each directory, which fires when entries are added,
+// removed or renamed, and each file, which fires on in-place writes. File
+// descriptors go stale when a file is replaced, so they are reopened after
+// every event.
+type kqueue struct {
+	kq int
+
+	mu    sync.Mutex
+	dirs  map[int]string
+	files map[int]string
+}
+
+func start(w *Watcher) (impl, error) {
+	kq, err := unix.Kqueue()
+	if err != nil {
+		return nil, fmt.Errorf("kqueue: %w", err)
+	}
+	k := &kqueue{kq: kq, dirs: make(map[int]string), files: make(map[int]string)}
+
+	for _, dir := range w.dirs() {
+		fd, err := k.add(dir)
+		if err != nil {
+			k.close()
+			return nil, fmt.Errorf("kqueue watch %s: %w", dir, err)
+		}
+		k.dirs[fd] = dir
+	}
+	for f := range w.files {
+		k.addFile(f)
+	}
+
+	go k.read(w)
+	return k, nil
+}
+
+// add opens path for event notifications only and registers it.
+func (k *kqueue) add(path string) (int, error) {
+	fd, err := unix.Open(path, unix.O_EVTONLY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return -1, err
+	}
+	var ev unix.Kevent_t
+	unix.SetKevent(&ev, fd, unix.EVFILT_VNODE, unix.EV_ADD|unix.EV_CLEAR|unix.EV_ENABLE)
+	ev.Fflags = kqueueFlags
+	if _, err := unix.Kevent(k.kq, []unix.Kevent_t{ev}, nil, nil); err != nil {
+		unix.Close(fd)
+		return -1, err
+	}
+	return fd, nil
+}
+
+// addFile watches path if it currently exists; a missing file is picked up
+// again through its directory once it is recreated.
+func (k *kqueue) addFile(path string) {
+	if fd, err := k.add(path); err == nil {
+		k.files[fd] = path
+	}
+}
+
+func (k *kqueue) read(w *Watcher) {
+	events := make([]unix.Kevent_t, 16)
+	timeout := unix.NsecToTimespec(int64(500 * time.Millisecond))
+
+	for {
+		select {
+		case <-w.done:
+			return
+		default:
+		}
+
+		n, err := unix.Kevent(k.kq, nil, events, &timeout)
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil {
+			return
+		}
+		if n == 0 {
+			continue
+		}
+
+		k.mu.Lock()
+		changed := make(map[string]bool)
+		for _, ev := range events[:n] {
+			fd := int(ev.Ident)
+			if path, ok := k.files[fd]; ok {
+				changed[path] = true
+			} else if dir, ok := k.dirs[fd]; ok {
+				for f := range w.files {
+					if filepath.Dir(f) == dir {
+						changed[f] = true
+					}
+				}
+			}
+		}
+		for fd := range k.files {
+			unix.Close(fd)
+			delete(k.files, fd)
+		}
+		for f := range w.files {
+			k.addFile(f)
+		}
+		k.mu.Unlock()
+
+		for path := range changed {
+			w.send(path)
+		}
+	}
+}
+
+func (k *kqueue) close() error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	for fd := range k.files {
+		unix.Close(fd)
+	}
+	for fd := range k.dirs {
+		unix.Close(fd)
+	}
+	return unix.Close(k.kq)
+}
@@ -0,0 +1,91 @@
+// Package watch reports changes to individual files using the platform's
+// filesystem notifications (inotify on Linux, kqueue on macOS).
+package watch
+
+import (
+	"errors"
+	"path/filepath"
+)
+
+// ErrUnsupported is returned by New on platforms without a notification
+// backend. Callers should fall back to polling.
+var ErrUnsupported = errors.New("file watching is not supported on this platform")
+
+// Watcher delivers the path of a watched file each time it is written,
+// replaced, or removed. Files are watched through their parent directory, so
+// a file that is deleted and recreated (or replaced by rename, as most
+// editors and sc itself do) keeps being watched.
+type Watcher struct {
+	events chan string
+	done   chan struct{}
+	files  map[string]bool
+	impl   impl
+}
+
+type impl interface {
+	close() error
+}
+
+// New starts watching paths. The parent directory of each path must exist.
+func New(paths ...string) (*Watcher, error) {
+	w := &Watcher{
+		events: make(chan string, len(paths)),
+		done:   make(chan struct{}),
+		files:  make(map[string]bool, len(paths)),
+	}
+	for _, p := range paths {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			return nil, err
+		}
+		w.files[abs] = true
+	}
+
+	impl, err := start(w)
+	if err != nil {
+		return nil, err
+	}
+	w.impl = impl
+	return w, nil
+}
+
+// Events returns the channel changed paths are sent on, as they were passed
+// to New after being made absolute. A nil Watcher has a nil channel, which
+// never delivers.
+func (w *Watcher) Events() <-chan string {
+	if w == nil {
+		return nil
+	}
+	return w.events
+}
+
+// Close stops watching and releases the notification handle.
+func (w *Watcher) Close() error {
+	close(w.done)
+	return w.impl.close()
+}
+
+// dirs returns the parent directories of the watched files.
+func (w *Watcher) dirs() []string {
+	seen := make(map[string]bool)
+	var dirs []string
+	for f := range w.files {
+		dir := filepath.Dir(f)
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// send reports a change to path if it is one of the watched files.
+func (w *Watcher) send(path string) {
+	if !w.files[path] {
+		return
+	}
+	select {
+	case w.events <- path:
+	case <-w.done:
+	}
+}
@@ -0,0 +1,7 @@
+//go:build !linux && !darwin
+
+package watch
+
+func start(w *Watcher) (impl, error) {
+	return nil, ErrUnsupported
+}
@@ -0,0 +1,300 @@
+// Package scclient controls a running sc daemon over its unix socket.
+//
+// Every method takes a context: its deadline bounds the whole request,
+// including connecting, and cancelling it aborts the request. Requests the
+// daemon refuses are returned as *Error with a stable Code; a daemon on a
+// different protocol version yields *VersionError.
+package scclient
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+
+	"sc/internal/config"
+	"sc/internal/ipc"
+)
+
+// Client sends requests to the daemon. It holds no connection, so the zero
+// value with a socket path set is ready to use and it is safe for
+// concurrent use.
+type Client struct {
+	SockPath string
+}
+
+// New returns a client for the daemon listening on sockPath.
+func New(sockPath string) *Client {
+	return &Client{SockPath: sockPath}
+}
+
+// Default returns a client for the daemon at its standard socket path.
+func Default() *Client {
+	return New(config.SocketPath())
+}
+
+// Hello returns the daemon's release and protocol version.
+func (c *Client) Hello(ctx context.Context) (HelloData, error) {
+	cn, err := c.dial(ctx)
+	if err != nil {
+		return HelloData{}, err
+	}
+	defer cn.close()
+	return cn.hello, nil
+}
+
+// Status returns the state of every configured domain.
+func (c *Client) Status(ctx context.Context) (StatusData, error) {
+	var data StatusData
+	err := c.call(ctx, ipc.CmdStatus, nil, &data)
+	return data, err
+}
+
+// Unblock unblocks domains (which may include @group references) for dur,
+// or every domain if none are given. The daemon may shorten dur to fit a
+// limit or budget; the result reports the duration granted.
+func (c *Client) Unblock(ctx context.Context, domains []string, dur time.Duration) (UnblockData, error) {
+	var data UnblockData
+	err := c.call(ctx, ipc.CmdUnblock, ipc.UnblockRequest{Domains: domains, Duration: dur.String()}, &data)
+	return data, err
+}
+
+// Reblock ends the unblocks for domains, or all of them if none are given.
+func (c *Client) Reblock(ctx context.Context, domains []string) (ReblockData, error) {
+	var data ReblockData
+	err := c.call(ctx, ipc.CmdReblock, ipc.ReblockRequest{Domains: domains}, &data)
+	return data, err
+}
+
+// Add adds domains to the block list and, if group is not empty, to that
+// group.
+func (c *Client) Add(ctx context.Context, domains []string, group string) (MutateData, error) {
+	var data MutateData
+	err := c.call(ctx, ipc.CmdAdd, ipc.AddRequest{Domains: domains, Group: group}, &data)
+	return data, err
+}
+
+// Remove removes domains, or every member of an @group, from the block list.
+func (c *Client) Remove(ctx context.Context, domains []string) (MutateData, error) {
+	var data MutateData
+	err := c.call(ctx, ipc.CmdRemove, ipc.RemoveRequest{Domains: domains}, &data)
+	return data, err
+}
+
+// List returns the configured domains and groups, and with expanded every
+// host name written for each domain.
+func (c *Client) List(ctx context.Context, expanded bool) (ListData, error) {
+	var data ListData
+	err := c.call(ctx, ipc.CmdList, ipc.ListRequest{Expanded: expanded}, &data)
+	return data, err
+}
+
+// Focus reblocks everything and starts or extends a focus session.
+func (c *Client) Focus(ctx context.Context, dur time.Duration) (FocusData, error) {
+	var data FocusData
+	err := c.call(ctx, ipc.CmdFocus, ipc.FocusRequest{Duration: dur.String()}, &data)
+	return data, err
+}
+
+// Reload makes the daemon re-read its config file.
+func (c *Client) Reload(ctx context.Context) (ReloadData, error) {
+	var data ReloadData
+	err := c.call(ctx, ipc.CmdReload, nil, &data)
+	return data, err
+}
+
+// ApplyConfig hands the daemon new config file contents to validate, apply
+// and write.
+func (c *Client) ApplyConfig(ctx context.Context, raw []byte) (ReloadData, error) {
+	var data ReloadData
+	err := c.call(ctx, ipc.CmdApplyConfig, ipc.ApplyConfigRequest{Config: string(raw)}, &data)
+	return data, err
+}
+
+// Get returns the running value of a single setting such as
+// "settings.default_duration".
+func (c *Client) Get(ctx context.Context, key string) (SettingData, error) {
+	var data SettingData
+	err := c.call(ctx, ipc.CmdGet, ipc.GetRequest{Key: key}, &data)
+	return data, err
+}
+
+// Set changes, applies and saves a single setting.
+func (c *Client) Set(ctx context.Context, key, value string) (SettingData, error) {
+	var data SettingData
+	err := c.call(ctx, ipc.CmdSet, ipc.SetRequest{Key: key, Value: value}, &data)
+	return data, err
+}
+
+// call sends one request and decodes the response data into out.
+func (c *Client) call(ctx context.Context, command string, payload, out any) error {
+	cn, err := c.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer cn.close()
+
+	resp, err := cn.roundTrip(command, payload)
+	if err != nil {
+		return cn.wrap(err)
+	}
+	if err := json.Unmarshal(resp.Data, out); err != nil {
+		return fmt.Errorf("parsing %s response: %w", command, err)
+	}
+	return nil
+}
+
+// conn is a connection that has completed the hello handshake. It is
+// bound to the context it was dialled with.
+type conn struct {
+	net.Conn
+	ctx     context.Context
+	stop    func() bool
+	scanner *bufio.Scanner
+	hello   HelloData
+}
+
+// dial connects to the daemon and checks it speaks ipc.ProtocolVersion.
+func (c *Client) dial(ctx context.Context) (*conn, error) {
+	var d net.Dialer
+	nc, err := d.DialContext(ctx, "unix", c.SockPath)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, context.Cause(ctx)
+		}
+		return nil, ErrDaemonUnavailable
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		nc.SetDeadline(deadline)
+	}
+	cn := &conn{
+		Conn:    nc,
+		ctx:     ctx,
+		scanner: bufio.NewScanner(nc),
+		// Unblock any read or write in progress when ctx is cancelled.
+		stop: context.AfterFunc(ctx, func() { nc.SetDeadline(time.Now()) }),
+	}
+	if err := cn.handshake(); err != nil {
+		cn.close()
+		return nil, cn.wrap(err)
+	}
+	return cn, nil
+}
+
+func (cn *conn) close() {
+	cn.stop()
+	cn.Close()
+}
+
+// wrap reports why the context ended in place of the I/O error it caused.
+func (cn *conn) wrap(err error) error {
+	if cn.ctx.Err() != nil {
+		return context.Cause(cn.ctx)
+	}
+	return err
+}
+
+func (cn *conn) handshake() error {
+	if err := cn.write(ipc.Request{Version: ipc.ProtocolVersion, Command: ipc.CmdHello}); err != nil {
+		return err
+	}
+	line, err := cn.read()
+	if err != nil {
+		return err
+	}
+
+	// Daemons before versioning answer with a plain string error and no
+	// version field, so look at the version before decoding the rest.
+	var probe struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(line, &probe); err != nil {
+		return fmt.Errorf("parsing response: %w", err)
+	}
+	if probe.Version == 0 {
+		return &VersionError{ClientProtocol: ipc.ProtocolVersion, DaemonProtocol: 1}
+	}
+
+	var resp ipc.Response
+	if err := json.Unmarshal(line, &resp); err != nil {
+		return fmt.Errorf("parsing response: %w", err)
+	}
+	json.Unmarshal(resp.Data, &cn.hello)
+	if resp.Version != ipc.ProtocolVersion {
+		return &VersionError{
+			ClientProtocol: ipc.ProtocolVersion,
+			DaemonProtocol: resp.Version,
+			DaemonVersion:  cn.hello.DaemonVersion,
+		}
+	}
+	if !resp.OK {
+		return resp.Error
+	}
+	return nil
+}
+
+func (cn *conn) write(req ipc.Request) error {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	_, err = cn.Write(data)
+	return err
+}
+
+func (cn *conn) read() ([]byte, error) {
+	if !cn.scanner.Scan() {
+		if err := cn.scanner.Err(); err != nil {
+			return nil, fmt.Errorf("reading response: %w", err)
+		}
+		return nil, fmt.Errorf("no response from daemon")
+	}
+	return cn.scanner.Bytes(), nil
+}
+
+// roundTrip sends command with payload and returns the matching response.
+func (cn *conn) roundTrip(command string, payload any) (*ipc.Response, error) {
+	req := ipc.Request{Version: ipc.ProtocolVersion, ID: newID(), Command: command}
+	if payload != nil {
+		raw, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		req.Payload = raw
+	}
+	if err := cn.write(req); err != nil {
+		return nil, err
+	}
+
+	line, err := cn.read()
+	if err != nil {
+		return nil, err
+	}
+	var resp ipc.Response
+	if err := json.Unmarshal(line, &resp); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+	if resp.ID != req.ID {
+		return nil, fmt.Errorf("response %q does not match request %q", resp.ID, req.ID)
+	}
+	if !resp.OK {
+		if resp.Error == nil {
+			return nil, ipc.Errorf(ipc.CodeInternal, "request failed")
+		}
+		return nil, resp.Error
+	}
+	return &resp, nil
+}
+
+// newID returns a random request ID.
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
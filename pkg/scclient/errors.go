@@ -0,0 +1,61 @@
+package scclient
+
+import (
+	"errors"
+	"fmt"
+
+	"sc/internal/ipc"
+)
+
+// ErrDaemonUnavailable is returned when the daemon's socket cannot be
+// reached.
+var ErrDaemonUnavailable = errors.New("cannot connect to daemon — is it running? Try: sudo sc install")
+
+// Error is a request the daemon refused. Code is one of the Code constants
+// and is stable across releases; Message is meant for people.
+type Error = ipc.Error
+
+// Error codes.
+const (
+	CodeInvalidRequest   = ipc.CodeInvalidRequest
+	CodeUnknownCommand   = ipc.CodeUnknownCommand
+	CodeVersionMismatch  = ipc.CodeVersionMismatch
+	CodeInvalidDuration  = ipc.CodeInvalidDuration
+	CodeInvalidDomain    = ipc.CodeInvalidDomain
+	CodeInvalidValue     = ipc.CodeInvalidValue
+	CodeInvalidConfig    = ipc.CodeInvalidConfig
+	CodeNotFound         = ipc.CodeNotFound
+	CodePermissionDenied = ipc.CodePermissionDenied
+	CodeLocked           = ipc.CodeLocked
+	CodeCooldown         = ipc.CodeCooldown
+	CodeBudgetExhausted  = ipc.CodeBudgetExhausted
+	CodeInternal         = ipc.CodeInternal
+)
+
+// HasCode reports whether err is an *Error with the given code.
+func HasCode(err error, code string) bool {
+	var e *Error
+	return errors.As(err, &e) && e.Code == code
+}
+
+// VersionError is returned when the daemon speaks a different protocol
+// version, typically because only one side was upgraded. A DaemonProtocol
+// of 1 means a daemon from before protocol versioning.
+type VersionError struct {
+	ClientProtocol int
+	DaemonProtocol int
+	DaemonVersion  string
+}
+
+func (e *VersionError) Error() string {
+	daemon := "the running sc daemon"
+	if e.DaemonVersion != "" {
+		daemon += " " + e.DaemonVersion
+	}
+	if e.DaemonProtocol < e.ClientProtocol {
+		return fmt.Sprintf("%s is older than this client (protocol %d, client speaks %d) — restart it to finish upgrading: sudo sc install",
+			daemon, e.DaemonProtocol, e.ClientProtocol)
+	}
+	return fmt.Sprintf("%s is newer than this client (protocol %d, client speaks %d) — use the sc binary that matches the daemon",
+		daemon, e.DaemonProtocol, e.ClientProtocol)
+}
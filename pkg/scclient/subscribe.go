@@ -0,0 +1,52 @@
+package scclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"sc/internal/ipc"
+)
+
+// Subscription is an open event stream from the daemon.
+type Subscription struct {
+	conn *conn
+}
+
+// Subscribe opens a stream of daemon events. ctx bounds the whole
+// subscription: the stream ends when it is cancelled or its deadline
+// passes.
+func (c *Client) Subscribe(ctx context.Context) (*Subscription, error) {
+	cn, err := c.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := cn.roundTrip(ipc.CmdSubscribe, nil); err != nil {
+		cn.close()
+		return nil, cn.wrap(err)
+	}
+	return &Subscription{conn: cn}, nil
+}
+
+// Next blocks until the next event arrives. It returns io.EOF once the
+// daemon closes the stream, and the context's error once it is cancelled.
+func (s *Subscription) Next() (Event, error) {
+	if !s.conn.scanner.Scan() {
+		if err := s.conn.scanner.Err(); err != nil {
+			return Event{}, s.conn.wrap(err)
+		}
+		return Event{}, s.conn.wrap(io.EOF)
+	}
+	var ev Event
+	if err := json.Unmarshal(s.conn.scanner.Bytes(), &ev); err != nil {
+		return Event{}, fmt.Errorf("parsing event: %w", err)
+	}
+	return ev, nil
+}
+
+// Close ends the subscription.
+func (s *Subscription) Close() error {
+	s.conn.stop()
+	return s.conn.Close()
+}
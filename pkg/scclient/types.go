@@ -0,0 +1,31 @@
+package scclient
+
+import "sc/internal/ipc"
+
+// Response types, shared with the daemon's protocol.
+type (
+	HelloData   = ipc.HelloData
+	StatusData  = ipc.StatusData
+	StatusEntry = ipc.StatusEntry
+	UnblockData = ipc.UnblockData
+	ReblockData = ipc.ReblockData
+	MutateData  = ipc.MutateData
+	ListData    = ipc.ListData
+	FocusData   = ipc.FocusData
+	ReloadData  = ipc.ReloadData
+	SettingData = ipc.SettingData
+	Event       = ipc.Event
+)
+
+// Event types delivered by Subscribe.
+const (
+	EventUnblocked      = ipc.EventUnblocked
+	EventReblocked      = ipc.EventReblocked
+	EventExpiring       = ipc.EventExpiring
+	EventDomainAdded    = ipc.EventDomainAdded
+	EventDomainRemoved  = ipc.EventDomainRemoved
+	EventTamper         = ipc.EventTamper
+	EventConfigReloaded = ipc.EventConfigReloaded
+	EventConfigRefused  = ipc.EventConfigRefused
+	EventFocusStarted   = ipc.EventFocusStarted
+)